@@ -5,18 +5,37 @@ import (
 	"go_final_project/pkg/api"
 	"go_final_project/pkg/db"
 	"go_final_project/pkg/server"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
 
-	dbConn, err := db.Init()
+	store, err := db.NewStore()
 	if err != nil {
 		fmt.Println(err)
+		os.Exit(1)
 	}
-	defer dbConn.Close()
+	defer store.Close()
 
-	storage := db.NewStorage(dbConn)
-	service := api.NewTaskService(storage)
+	service := api.NewTaskService(store)
 
 	server.Run(service)
 }
+
+func migrate(args []string) int {
+	dbConn, dialect, err := db.OpenSchema()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer dbConn.Close()
+
+	if err = dialect.Run(dbConn, args, os.Stdout); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
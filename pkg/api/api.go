@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"go_final_project/pkg/utils"
 )
 
 type Response struct {
@@ -10,10 +13,71 @@ type Response struct {
 	Error string `json:"error,omitempty"`
 }
 
+type OccurrencesResp struct {
+	Dates []string `json:"dates"`
+}
+
+type DescribeResp struct {
+	Repeat string `json:"repeat"`
+	Text   string `json:"text"`
+}
+
+type ParseReq struct {
+	Text  string `json:"text"`
+	Lang  string `json:"lang"`
+	Date  string `json:"date"`
+	TZ    string `json:"tz"`
+	Limit int    `json:"limit"`
+}
+
+type ParseResp struct {
+	Repeat string   `json:"repeat"`
+	Text   string   `json:"text"`
+	Dates  []string `json:"dates"`
+}
+
+type ValidateResp struct {
+	Valid  bool   `json:"valid"`
+	Repeat string `json:"repeat"`
+}
+
+// RuleErrorResp is an error that points at a part of a repeat rule or phrase.
+// When several rules of a union are invalid, Errors lists each of them and
+// the other fields describe the first one.
+type RuleErrorResp struct {
+	Error  string          `json:"error"`
+	Kind   string          `json:"kind,omitempty"`
+	Code   string          `json:"code,omitempty"`
+	Token  string          `json:"token,omitempty"`
+	Offset *int            `json:"offset,omitempty"`
+	Errors []RuleErrorResp `json:"errors,omitempty"`
+}
+
 func Init(ts TaskService) {
 	http.HandleFunc("/api/nextdate", ts.nextDayHandler)
 	http.HandleFunc("/api/tasks", ts.tasksHandler)
 	http.HandleFunc("/api/task/done", ts.taskDoneHandler)
+	http.HandleFunc("/api/task/exceptions", ts.exceptionsHandler)
+	http.HandleFunc("/api/occurrences", ts.occurrencesHandler)
+	http.HandleFunc("/api/repeat/describe", ts.describeRepeatHandler)
+	http.HandleFunc("/api/repeat/parse", ts.parseRepeatHandler)
+	http.HandleFunc("/api/repeat/validate", ts.validateRepeatHandler)
+	http.HandleFunc("/api/holidays/import", ts.holidaysImportHandler)
+
+	http.HandleFunc("/api/holidays", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ts.getHolidaysHandler(w, r)
+		case http.MethodPost:
+			ts.addHolidayHandler(w, r)
+		case http.MethodPut:
+			ts.updateHolidayHandler(w, r)
+		case http.MethodDelete:
+			ts.deleteHolidayHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 
 	http.HandleFunc("/api/task", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -36,6 +100,34 @@ func responseError(w http.ResponseWriter, message string, statusCode int) {
 	writeJSON(w, response, statusCode)
 }
 
+// ruleError responds with the details of a *utils.RuleError, utils.RuleErrors
+// or *utils.PhraseError, and like responseError for any other error.
+func ruleError(w http.ResponseWriter, err error, statusCode int) {
+	response := RuleErrorResp{Error: err.Error()}
+
+	var ruleErrs utils.RuleErrors
+	var ruleErr *utils.RuleError
+	var phraseErr *utils.PhraseError
+	if errors.As(err, &ruleErrs) {
+		for _, ruleErr := range ruleErrs {
+			response.Errors = append(response.Errors, RuleErrorResp{
+				Error: ruleErr.Message, Kind: ruleErr.Kind, Code: ruleErr.Code,
+				Token: ruleErr.Token, Offset: &ruleErr.Offset,
+			})
+		}
+	}
+
+	switch {
+	case errors.As(err, &ruleErr):
+		response.Kind, response.Code = ruleErr.Kind, ruleErr.Code
+		response.Token, response.Offset = ruleErr.Token, &ruleErr.Offset
+	case errors.As(err, &phraseErr) && phraseErr.Token != "":
+		response.Token, response.Offset = phraseErr.Token, &phraseErr.Offset
+	}
+
+	writeJSON(w, response, statusCode)
+}
+
 func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(statusCode)
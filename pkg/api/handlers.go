@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,14 +12,44 @@ import (
 	"go_final_project/pkg/utils"
 )
 
+const (
+	defaultOccurrences = 50
+	maxOccurrences     = 1000
+
+	// defaultGrade is the recall grade of a spaced-repetition task marked
+	// done without one: a correct answer after some hesitation.
+	defaultGrade = 4
+)
+
 type TaskService struct {
-	store db.Storage
+	store db.TaskStore
 }
 
-func NewTaskService(store db.Storage) TaskService {
+func NewTaskService(store db.TaskStore) TaskService {
 	return TaskService{store: store}
 }
 
+// taskRule parses the repeat rule of a task with its end conditions and
+// applies its exception dates. Tasks that are not saved yet have no exceptions.
+func (t TaskService) taskRule(task *db.Task) (utils.Rule, error) {
+	calendar, err := t.store.HolidayCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := task.Rule(calendar)
+	if err != nil || task.ID == 0 {
+		return rule, err
+	}
+
+	exceptions, err := t.store.ExceptionDates(task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.WithExceptions(rule, exceptions), nil
+}
+
 func (t TaskService) tasksHandler(w http.ResponseWriter, r *http.Request) {
 	search := r.URL.Query().Get("search")
 	limit := r.URL.Query().Get("limit")
@@ -26,12 +57,26 @@ func (t TaskService) tasksHandler(w http.ResponseWriter, r *http.Request) {
 		limit = "50"
 	}
 
-	tasks, err := t.store.GetTasks(search, limit)
+	getTasks := t.store.GetTasks
+	if r.URL.Query().Get("fuzzy") == "1" {
+		getTasks = t.store.GetFuzzyTasks
+	}
+
+	tasks, err := getTasks(search, limit)
+	if errors.Is(err, db.ErrInvalidSearch) {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		responseError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	lang := r.URL.Query().Get("lang")
+	for i := range tasks {
+		tasks[i].RepeatText = describeRepeat(tasks[i].Repeat, lang)
+	}
+
 	response := db.TasksResp{Tasks: tasks}
 	writeJSON(w, response, http.StatusOK)
 }
@@ -51,17 +96,39 @@ func (t TaskService) updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = task.Validate(); err != nil {
-		responseError(w, err.Error(), http.StatusBadRequest)
+	stored, err := t.store.GetTask(task.ID)
+	if err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	task.Done = stored.Done
+	task.SetReview(stored.Review())
+	if task.Date == stored.Date && task.Repeat == stored.Repeat {
+		task.Anchor = stored.Anchor
+	}
+
+	calendar, err := t.store.HolidayCalendar()
+	if err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	err = t.store.UpdateTask(&task)
+	exceptions, err := t.store.ExceptionDates(task.ID)
 	if err != nil {
 		responseError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err = task.Validate(calendar, exceptions); err != nil {
+		ruleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err = t.store.AdvanceTask(&task, task.Missed(), false); err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	writeJSON(w, map[string]interface{}{}, http.StatusOK)
 }
 
@@ -90,6 +157,18 @@ func (t TaskService) getTaskHandler(w http.ResponseWriter, r *http.Request) {
 		Title:   task.Title,
 		Comment: task.Comment,
 		Repeat:  task.Repeat,
+		Time:    task.Time,
+		TZ:      task.TZ,
+		Until:   task.Until,
+		Count:   task.Count,
+		Done:    task.Done,
+		CatchUp: task.CatchUp,
+
+		Ease:     task.Ease,
+		Interval: task.Interval,
+		Reps:     task.Reps,
+
+		RepeatText: describeRepeat(task.Repeat, r.URL.Query().Get("lang")),
 	}
 	writeJSON(w, response, http.StatusOK)
 }
@@ -109,8 +188,17 @@ func (t TaskService) taskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = task.Validate(); err != nil {
-		responseError(w, err.Error(), http.StatusBadRequest)
+	task.Done = 0
+	task.SetReview(utils.Review{})
+
+	calendar, err := t.store.HolidayCalendar()
+	if err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = task.Validate(calendar, nil); err != nil {
+		ruleError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -141,6 +229,15 @@ func (t TaskService) taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	grade := defaultGrade
+	if gradeStr := r.URL.Query().Get("grade"); gradeStr != "" {
+		grade, err = strconv.Atoi(gradeStr)
+		if err != nil || grade < 0 || grade > utils.MaxGrade {
+			responseError(w, "invalid grade, expected 0 to 5", http.StatusBadRequest)
+			return
+		}
+	}
+
 	task, err := t.store.GetTask(parsedId)
 	if err != nil {
 		responseError(w, err.Error(), http.StatusInternalServerError)
@@ -157,21 +254,28 @@ func (t TaskService) taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	parsedDate, err := time.Parse(utils.DateFormat, task.Date)
+	rule, err := t.taskRule(task)
 	if err != nil {
-		responseError(w, "invalid date format, expected YYYYMMDD", http.StatusBadRequest)
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	nextDate, err := utils.NextDate(parsedDate, task.Date, task.Repeat)
-	if err != nil {
+	if review, ok := utils.ReviewOf(rule); ok {
+		task.SetReview(review.Grade(grade))
+		if rule, err = t.taskRule(task); err != nil {
+			responseError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	missed, err := task.Advance(rule, time.Now(), true)
+	finished := errors.Is(err, utils.ErrNoOccurrences)
+	if err != nil && !finished {
 		responseError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	task.Date = nextDate
-
-	err = t.store.UpdateTask(task)
-	if err != nil {
+	if err = t.store.AdvanceTask(task, missed, finished); err != nil {
 		responseError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -216,18 +320,230 @@ func (t TaskService) nextDayHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now, err := time.Parse(utils.DateFormat, nowStr)
+	task, err := adhocTask(dateStr, repeat, r.FormValue("tz"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc, err := task.Location()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now, _, err := utils.ParseDateTime(nowStr, loc)
 	if err != nil {
 		http.Error(w, "invalid format for 'now': "+nowStr, http.StatusBadRequest)
 		return
 	}
 
-	nextDate, err := utils.NextDate(now, dateStr, repeat)
+	rule, err := t.taskRule(task)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := task.Start()
+	if err != nil {
+		http.Error(w, "invalid format for 'date': "+dateStr, http.StatusBadRequest)
+		return
+	}
+
+	nextDate, err := utils.NextAfter(rule, startDate, now)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(nextDate))
+	w.Write([]byte(formatStart(task, nextDate)))
+}
+
+func (t TaskService) occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var task *db.Task
+	var adhoc bool
+	if id := query.Get("id"); id != "" {
+		parsedId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			responseError(w, "invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		task, err = t.store.GetTask(parsedId)
+		if err != nil {
+			responseError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if query.Get("date") == "" || query.Get("repeat") == "" {
+		responseError(w, "missing required parameters: id or date and repeat", http.StatusBadRequest)
+		return
+	} else {
+		var err error
+		adhoc = true
+		task, err = adhocTask(query.Get("date"), query.Get("repeat"), query.Get("tz"))
+		if err != nil {
+			responseError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	start, err := task.Start()
+	if err != nil {
+		responseError(w, "invalid format for 'date': "+task.Date, http.StatusBadRequest)
+		return
+	}
+	loc := start.Location()
+
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if fromStr := query.Get("from"); fromStr != "" {
+		if from, err = time.ParseInLocation(utils.DateFormat, fromStr, loc); err != nil {
+			responseError(w, "invalid format for 'from': "+fromStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	to := from.AddDate(0, 1, 0)
+	if toStr := query.Get("to"); toStr != "" {
+		if to, err = time.ParseInLocation(utils.DateFormat, toStr, loc); err != nil {
+			responseError(w, "invalid format for 'to': "+toStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultOccurrences
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxOccurrences {
+			responseError(w, "invalid limit: "+limitStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if to.Before(from) {
+		responseError(w, "'to' is before 'from'", http.StatusBadRequest)
+		return
+	}
+	to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	var dates []time.Time
+	if task.Repeat == "" {
+		if !start.Before(from) && !start.After(to) {
+			dates = append(dates, start)
+		}
+	} else {
+		rule, err := t.taskRule(task)
+		if err != nil {
+			responseError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// An ad-hoc date only marks where the series begins, while a task's
+		// date is always due.
+		if adhoc {
+			start = utils.FirstOccurrence(rule, start)
+		}
+		if !start.IsZero() {
+			dates = utils.OccurrencesOf(rule, start, from, to, limit)
+		}
+	}
+
+	response := OccurrencesResp{Dates: []string{}}
+	for _, date := range dates {
+		response.Dates = append(response.Dates, formatStart(task, date))
+	}
+	writeJSON(w, response, http.StatusOK)
+}
+
+func (t TaskService) exceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		responseError(w, "task ID is required", http.StatusBadRequest)
+		return
+	}
+
+	parsedId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		responseError(w, "invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		dates, err := t.store.GetExceptions(parsedId)
+		if err != nil {
+			responseError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, db.ExceptionsResp{Dates: dates}, http.StatusOK)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if _, err = time.Parse(utils.DateFormat, date); err != nil {
+		responseError(w, "invalid date format, expected YYYYMMDD", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		task, err := t.store.GetTask(parsedId)
+		if err != nil {
+			responseError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if task.Repeat == "" {
+			responseError(w, "task does not repeat", http.StatusBadRequest)
+			return
+		}
+		err = t.store.AddException(parsedId, date)
+	case http.MethodDelete:
+		err = t.store.DeleteException(parsedId, date)
+	default:
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{}, http.StatusOK)
+}
+
+// adhocTask builds an unsaved task from request parameters, where date may
+// carry a time of day in utils.DateTimeFormat.
+func adhocTask(date, repeat, tz string) (*db.Task, error) {
+	task := &db.Task{Date: date, Repeat: repeat, TZ: tz}
+	loc, err := task.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	start, timed, err := utils.ParseDateTime(date, loc)
+	if err != nil {
+		return nil, errors.New("invalid format for 'date': " + date)
+	}
+	if timed {
+		task.Date = start.Format(utils.DateFormat)
+		task.Time = start.Format(utils.TimeFormat)
+	} else if rule, err := utils.ParseRule(repeat); err == nil {
+		if first, ok := utils.FirstTime(rule); ok {
+			task.Time = first
+		}
+	}
+	return task, nil
+}
+
+func formatStart(task *db.Task, date time.Time) string {
+	if task.Time != "" {
+		return date.Format(utils.DateTimeFormat)
+	}
+	return date.Format(utils.DateFormat)
 }
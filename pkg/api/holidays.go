@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"go_final_project/pkg/db"
+)
+
+type ImportResp struct {
+	Imported int `json:"imported"`
+}
+
+func (t TaskService) getHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	holidays, err := t.store.GetHolidays()
+	if err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, db.HolidaysResp{Holidays: holidays}, http.StatusOK)
+}
+
+func (t TaskService) addHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	holiday, ok := readHoliday(w, r)
+	if !ok {
+		return
+	}
+
+	if err := t.store.AddHolidays([]db.Holiday{holiday}); err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{}, http.StatusOK)
+}
+
+func (t TaskService) updateHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	holiday, ok := readHoliday(w, r)
+	if !ok {
+		return
+	}
+
+	if err := t.store.UpdateHoliday(&holiday); err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{}, http.StatusOK)
+}
+
+func (t TaskService) deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	holiday := db.Holiday{Date: r.URL.Query().Get("date")}
+	if holiday.Date == "" {
+		responseError(w, "holiday date is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := holiday.Validate(); err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := t.store.DeleteHoliday(holiday.Date); err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{}, http.StatusOK)
+}
+
+func (t TaskService) holidaysImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		responseError(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+
+	holidays, err := db.ParseHolidays(buf.String())
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = t.store.AddHolidays(holidays); err != nil {
+		responseError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, ImportResp{Imported: len(holidays)}, http.StatusOK)
+}
+
+func readHoliday(w http.ResponseWriter, r *http.Request) (db.Holiday, bool) {
+	var holiday db.Holiday
+	var buf bytes.Buffer
+
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		responseError(w, "failed to read the request body", http.StatusBadRequest)
+		return holiday, false
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &holiday); err != nil {
+		responseError(w, "failed to deserialize JSON", http.StatusBadRequest)
+		return holiday, false
+	}
+
+	if err := holiday.Validate(); err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return holiday, false
+	}
+
+	return holiday, true
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+	"unicode"
+
+	"go_final_project/pkg/db"
+	"go_final_project/pkg/utils"
+)
+
+const (
+	defaultPreviewDates = 5
+	previewSearchYears  = 100
+)
+
+func (t TaskService) describeRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repeat := r.URL.Query().Get("repeat")
+	rule, err := utils.ParseRule(repeat)
+	if err != nil {
+		ruleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	text, err := utils.Describe(rule, r.URL.Query().Get("lang"))
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, DescribeResp{Repeat: rule.String(), Text: text}, http.StatusOK)
+}
+
+// validateRepeatHandler checks a repeat rule and returns its canonical form,
+// or the position of the first error for the client to highlight.
+func (t TaskService) validateRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rule, err := utils.ParseRule(r.URL.Query().Get("repeat"))
+	if err != nil {
+		ruleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, ValidateResp{Valid: true, Repeat: rule.String()}, http.StatusOK)
+}
+
+// parseRepeatHandler turns a natural-language phrase into a repeat rule and
+// previews its first dates starting from the given or the current date.
+func (t TaskService) parseRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		responseError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ParseReq
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		responseError(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		responseError(w, "failed to deserialize JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Limit == 0 {
+		req.Limit = defaultPreviewDates
+	}
+	if req.Limit < 1 || req.Limit > maxOccurrences {
+		responseError(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	loc, err := (&db.Task{TZ: req.TZ}).Location()
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Date == "" {
+		req.Date = time.Now().In(loc).Format(utils.DateFormat)
+	}
+	date, _, err := utils.ParseDateTime(req.Date, loc)
+	if err != nil {
+		responseError(w, "invalid format for 'date': "+req.Date, http.StatusBadRequest)
+		return
+	}
+
+	repeat, err := utils.ParseNatural(req.Text, date)
+	if err != nil {
+		ruleError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	lang := req.Lang
+	if lang == "" {
+		lang = phraseLanguage(req.Text)
+	}
+	text, err := utils.DescribeRule(repeat, lang)
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := adhocTask(req.Date, repeat, req.TZ)
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start, err := task.Start()
+	if err != nil {
+		responseError(w, "invalid format for 'date': "+task.Date, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := t.taskRule(task)
+	if err != nil {
+		responseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The date only marks where the preview begins, so it is listed only when
+	// the rule occurs on it.
+	dates := []time.Time{}
+	if first := utils.FirstOccurrence(rule, start); !first.IsZero() {
+		dates = utils.OccurrencesOf(rule, first, first, first.AddDate(previewSearchYears, 0, 0), req.Limit)
+	}
+	response := ParseResp{Repeat: repeat, Text: text, Dates: make([]string, len(dates))}
+	for i, date := range dates {
+		response.Dates[i] = formatStart(task, date)
+	}
+	writeJSON(w, response, http.StatusOK)
+}
+
+// phraseLanguage describes a phrase in the language it was written in.
+func phraseLanguage(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return utils.LangRussian
+		}
+	}
+	return utils.LangEnglish
+}
+
+// describeRepeat returns the description of a stored task's repeat rule, or
+// an empty string when the task does not repeat or the language is unknown.
+func describeRepeat(repeat, lang string) string {
+	if repeat == "" {
+		return ""
+	}
+	text, err := utils.DescribeRule(repeat, lang)
+	if err != nil {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go_final_project/pkg/utils"
+)
+
+// Catch-up policies decide where a repeating task goes when its date has
+// passed: to its first occurrence after today, to the very next occurrence
+// even if that is still in the past, or after today with a separate one-off
+// task for every occurrence that was missed.
+const (
+	CatchUpSkip = "skip"
+	CatchUpOne  = "one"
+	CatchUpEach = "each"
+)
+
+// maxCatchUpTasks bounds the tasks created for missed occurrences at once;
+// only the latest ones get a task.
+const maxCatchUpTasks = 100
+
+var (
+	defaultCatchUp     string
+	defaultCatchUpOnce sync.Once
+)
+
+func validCatchUp(policy string) bool {
+	return policy == CatchUpSkip || policy == CatchUpOne || policy == CatchUpEach
+}
+
+// DefaultCatchUp is the policy of tasks without their own one, taken from
+// TODO_CATCHUP and falling back to skip.
+func DefaultCatchUp() string {
+	defaultCatchUpOnce.Do(func() {
+		defaultCatchUp = CatchUpSkip
+		if policy := os.Getenv("TODO_CATCHUP"); validCatchUp(policy) {
+			defaultCatchUp = policy
+		}
+	})
+	return defaultCatchUp
+}
+
+func (task *Task) CatchUpPolicy() string {
+	if task.CatchUp == "" {
+		return DefaultCatchUp()
+	}
+	return task.CatchUp
+}
+
+// Advance moves a repeating task along rule according to its catch-up policy
+// and returns the one-off tasks for the occurrences it missed. Occurrences
+// count as missed up to today, or up to now for rules repeating within a day.
+// A completed task always leaves its current date; otherwise only a date
+// before that is moved, and the current date counts as missed. The task's
+// done counter grows by every occurrence used up, whether it was completed,
+// split off or skipped, so that a COUNT holds under every policy.
+// ErrNoOccurrences is returned, along with the missed tasks, when the rule has
+// nothing left after them.
+func (task *Task) Advance(rule utils.Rule, now time.Time, completed bool) ([]Task, error) {
+	return task.advance(rule, now, completed, task.CatchUpPolicy())
+}
+
+func (task *Task) advance(rule utils.Rule, now time.Time, completed bool, policy string) ([]Task, error) {
+	start, err := task.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.In(start.Location())
+	if !utils.IsSubDaily(rule) {
+		now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	if !completed && !start.Before(now) {
+		return nil, nil
+	}
+
+	// Completion-relative rules count from now whatever the policy.
+	switch {
+	case utils.IsRelative(rule):
+		policy = CatchUpSkip
+	case policy == CatchUpOne && !completed:
+		return nil, nil
+	case policy == CatchUpOne || start.After(now):
+		now = start
+	}
+
+	var missed []Task
+	if policy == CatchUpEach {
+		from := start
+		if completed {
+			from = start.Add(time.Nanosecond)
+		}
+		for _, date := range lastOccurrences(rule, start, from, now, maxCatchUpTasks) {
+			one := Task{Title: task.Title, Comment: task.Comment, Time: task.Time, TZ: task.TZ}
+			one.SetStart(date)
+			missed = append(missed, one)
+		}
+	}
+
+	switch count := countOf(rule); {
+	case count > 0 && !utils.IsRelative(rule):
+		// The rule stops after the remaining occurrences, so they are few.
+		task.Done += int64(len(utils.OccurrencesOf(rule, start, start, now, count)))
+	case completed:
+		task.Done += 1 + int64(len(missed))
+	default:
+		task.Done += int64(len(missed))
+	}
+
+	next, err := utils.NextAfter(rule, start, now)
+	if err != nil {
+		return missed, err
+	}
+	if task.Anchor == "" {
+		task.Anchor = task.Date
+	}
+	task.SetStart(next)
+
+	return missed, nil
+}
+
+// lastOccurrences returns at most max latest occurrences of rule between
+// from and to.
+func lastOccurrences(rule utils.Rule, start, from, to time.Time, max int) []time.Time {
+	dates := []time.Time{}
+	for {
+		page := utils.OccurrencesOf(rule, start, from, to, max)
+		dates = append(dates, page...)
+		if len(dates) > max {
+			dates = dates[len(dates)-max:]
+		}
+		if len(page) < max {
+			return dates
+		}
+		from = page[len(page)-1].Add(time.Nanosecond)
+	}
+}
+
+func checkCatchUp(policy string) error {
+	if policy != "" && !validCatchUp(policy) {
+		return errors.New("unknown catch-up policy: " + policy)
+	}
+	return nil
+}
+
+func saveCatchUp(db execer, task *Task) error {
+	if task.CatchUp == "" {
+		if _, err := db.Exec(`DELETE FROM task_catchup WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("failed to delete catch-up policy: %w", err)
+		}
+		return nil
+	}
+
+	query := `INSERT INTO task_catchup (task_id, policy) VALUES (?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET policy = excluded.policy`
+	if _, err := db.Exec(query, task.ID, task.CatchUp); err != nil {
+		return fmt.Errorf("failed to save catch-up policy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go_final_project/pkg/utils"
+)
+
+func TestAdvanceCount(t *testing.T) {
+	// The task started on January 1 and is completed ten days late.
+	now := time.Date(2024, time.January, 11, 15, 0, 0, 0, DefaultLocation())
+	tests := []struct {
+		policy string
+		count  int64
+		date   string
+		done   int64
+		missed int
+		err    error
+	}{
+		{CatchUpSkip, 20, "20240112", 11, 0, nil},
+		{CatchUpOne, 20, "20240102", 1, 0, nil},
+		{CatchUpEach, 20, "20240112", 11, 10, nil},
+		{CatchUpSkip, 5, "20240101", 5, 0, utils.ErrNoOccurrences},
+		{CatchUpOne, 5, "20240102", 1, 0, nil},
+		{CatchUpEach, 5, "20240101", 5, 4, utils.ErrNoOccurrences},
+	}
+
+	for _, tt := range tests {
+		task := Task{Date: "20240101", Title: "Зарядка", Repeat: "d 1", Count: tt.count, CatchUp: tt.policy}
+		rule, err := task.Rule(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		missed, err := task.Advance(rule, now, true)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s count %d: error %v, want %v", tt.policy, tt.count, err, tt.err)
+		}
+		if task.Date != tt.date || task.Done != tt.done || len(missed) != tt.missed {
+			t.Errorf("%s count %d: date %s, done %d, %d missed; want %s, %d, %d",
+				tt.policy, tt.count, task.Date, task.Done, len(missed), tt.date, tt.done, tt.missed)
+		}
+		if err != nil {
+			continue
+		}
+
+		// Completing the task on time from then on uses up exactly the rest
+		// of the count, the last completion ending it.
+		for {
+			start, err := task.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule, err = task.Rule(nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = task.Advance(rule, start, true); errors.Is(err, utils.ErrNoOccurrences) {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if task.Done != tt.count {
+			t.Errorf("%s count %d: ended with done %d", tt.policy, tt.count, task.Done)
+		}
+		if last := time.Date(2024, time.January, int(tt.count), 0, 0, 0, 0, time.UTC).Format(utils.DateFormat); task.Date != last {
+			t.Errorf("%s count %d: last occurrence on %s, want %s", tt.policy, tt.count, task.Date, last)
+		}
+	}
+}
@@ -3,55 +3,79 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	_ "modernc.org/sqlite"
 	"os"
-)
+	"strconv"
+	"strings"
+
+	"go_final_project/pkg/migrations"
 
-const (
-	schema = `CREATE TABLE scheduler
-                (
-                    id      INTEGER PRIMARY KEY AUTOINCREMENT,
-                    date    CHAR(8) NOT NULL DEFAULT "",
-                    title   CHAR(255),
-                    comment TEXT,
-                    repeat  CHAR(128)
-                );
-              CREATE INDEX idx_scheduler_date ON scheduler (date);`
+	"github.com/jmoiron/sqlx"
 )
 
-func Init() (*sql.DB, error) {
+// lockParams make concurrent writers wait for the lock instead of failing with
+// SQLITE_BUSY. Transactions take the write lock up front, since SQLite cannot
+// wait to upgrade a read lock while another transaction writes. Foreign keys
+// are off by default in SQLite; the side tables of a task rely on them to be
+// deleted along with it.
+const lockParams = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate"
+
+// dialect holds what differs between the databases SQLStore runs on.
+type dialect struct {
+	migrations *migrations.Dialect
+	bind       int
+	// search selects the tasks matching the full-text query given as its
+	// first argument, best matches first, each followed by a snippet with
+	// the matches between snippetOpen and snippetClose.
+	search      string
+	searchQuery func(q searchQuery) string
+}
+
+var sqliteDialect = &dialect{
+	migrations: migrations.SQLite,
+	bind:       sqlx.QUESTION,
+	search: `SELECT` + taskColumns + `,
+	       snippet(scheduler_fts, -1, char(2), char(3), '` + snippetEllipsis + `', ` + strconv.Itoa(snippetTokens) + `)
+	FROM scheduler_fts
+	JOIN scheduler ON scheduler.id = scheduler_fts.rowid` + taskJoins + `
+	WHERE scheduler_fts MATCH ?
+	ORDER BY scheduler_fts.rank, scheduler.date, COALESCE(t.time, ''), scheduler.id
+	LIMIT ?`,
+	searchQuery: searchQuery.fts5,
+}
+
+// Open opens the database file named by TODO_DBFILE, or ./scheduler.db,
+// without touching its schema.
+func Open() (*sql.DB, error) {
 	dbFile := "./scheduler.db"
 	envFile := os.Getenv("TODO_DBFILE")
 	if len(envFile) > 0 {
 		dbFile = envFile
 	}
 
-	_, err := os.Stat(dbFile)
+	sep := "?"
+	if strings.Contains(dbFile, "?") {
+		sep = "&"
+	}
 
-	var install bool
+	db, err := sql.Open("sqlite", dbFile+sep+lockParams)
 	if err != nil {
-		install = true
+		return nil, fmt.Errorf("error while open db: %w", err)
 	}
+	return db, nil
+}
 
-	db, err := sql.Open("sqlite", dbFile)
+// Init opens the database and brings its schema up to date.
+func Init() (*sql.DB, error) {
+	db, err := Open()
 	if err != nil {
-		return nil, fmt.Errorf("error while open db: %w", err)
+		return nil, err
 	}
 
-	if install {
-		if err = createTable(db); err != nil {
-			return nil, err
-		}
+	if err = sqliteDialect.migrations.Up(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate db: %w", err)
 	}
 
 	return db, nil
 }
-
-func createTable(db *sql.DB) error {
-	if _, err := db.Exec(schema); err != nil {
-		log.Fatalf("failed to create table: %v", err)
-		return err
-	}
-	return nil
-}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+type ExceptionsResp struct {
+	Dates []string `json:"dates"`
+}
+
+func (s SQLStore) AddException(taskID int64, date string) error {
+	query := `INSERT INTO task_exceptions (task_id, date) VALUES (?, ?) ON CONFLICT DO NOTHING`
+	if _, err := s.conn.Exec(query, taskID, date); err != nil {
+		return fmt.Errorf("failed to insert exception: %w", err)
+	}
+	return nil
+}
+
+func (s SQLStore) GetExceptions(taskID int64) ([]string, error) {
+	query := `SELECT date FROM task_exceptions WHERE task_id = ? ORDER BY date`
+	rows, err := s.conn.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	dates := []string{}
+	for rows.Next() {
+		var date string
+		if err = rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to parse exceptions: %w", err)
+		}
+		dates = append(dates, date)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate exceptions: %w", err)
+	}
+
+	return dates, nil
+}
+
+func (s SQLStore) DeleteException(taskID int64, date string) error {
+	query := `DELETE FROM task_exceptions WHERE task_id = ? AND date = ?`
+	res, err := s.conn.Exec(query, taskID, date)
+	if err != nil {
+		return fmt.Errorf("failed to delete exception: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("exception not found")
+	}
+
+	return nil
+}
+
+func (s SQLStore) ExceptionDates(taskID int64) ([]time.Time, error) {
+	dates, err := s.GetExceptions(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return exceptionDates(dates)
+}
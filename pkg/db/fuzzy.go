@@ -0,0 +1,299 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go_final_project/pkg/fuzzy"
+)
+
+// wordIndex is the auxiliary index of the fuzzy search: the words of the
+// tasks and the trigrams of those words.
+type wordIndex interface {
+	// sharedTrigrams returns the indexed words that have some of the
+	// trigrams, with how many of them each has.
+	sharedTrigrams(trigrams []string) (map[string]int, error)
+	// wordTasks returns the IDs of the tasks that contain each of the words.
+	wordTasks(words []string) (map[string][]int64, error)
+}
+
+// fuzzyMatch is a task found by a fuzzy search, with the mean similarity of
+// its best words and the words that matched.
+type fuzzyMatch struct {
+	id    int64
+	score float64
+	words map[string]bool
+}
+
+// fuzzySearch finds the tasks with a word similar to every word of search,
+// most similar first. Similar words are looked up by their trigrams among
+// the indexed words, and only then their tasks.
+func fuzzySearch(search string, index wordIndex) ([]fuzzyMatch, error) {
+	words := fuzzy.Words(search)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var matches map[int64]*fuzzyMatch
+	for i, word := range words {
+		trigrams := fuzzy.Trigrams(word)
+		shared, err := index.sharedTrigrams(trigrams)
+		if err != nil {
+			return nil, err
+		}
+
+		similarities := make(map[string]float64)
+		var similar []string
+		for candidate, count := range shared {
+			similarity := fuzzy.Jaccard(count, len(trigrams), len(fuzzy.Trigrams(candidate)))
+			if similarity >= fuzzy.Threshold {
+				similarities[candidate] = similarity
+				similar = append(similar, candidate)
+			}
+		}
+		if len(similar) == 0 {
+			return nil, nil
+		}
+
+		tasks, err := index.wordTasks(similar)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make(map[int64]*fuzzyMatch)
+		best := make(map[int64]float64)
+		for candidate, ids := range tasks {
+			for _, id := range ids {
+				m, ok := next[id]
+				if !ok {
+					if i == 0 {
+						m = &fuzzyMatch{id: id, words: make(map[string]bool)}
+					} else if m = matches[id]; m == nil {
+						continue
+					}
+					next[id] = m
+				}
+				m.words[candidate] = true
+				best[id] = max(best[id], similarities[candidate])
+			}
+		}
+
+		for id, similarity := range best {
+			next[id].score += similarity
+		}
+		matches = next
+	}
+
+	result := make([]fuzzyMatch, 0, len(matches))
+	for _, m := range matches {
+		m.score /= float64(len(words))
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].score != result[j].score {
+			return result[i].score > result[j].score
+		}
+		return result[i].id < result[j].id
+	})
+	return result, nil
+}
+
+// fuzzySnippet marks the matched words in the title or the comment.
+func fuzzySnippet(task Task, words map[string]bool) string {
+	columns := []string{task.Title, task.Comment}
+	tokens := make([][]token, len(columns))
+	hits := make([]map[int]bool, len(columns))
+	for c, column := range columns {
+		tokens[c] = tokenize(column)
+		hits[c] = make(map[int]bool)
+		for i, token := range tokens[c] {
+			if words[token.word] {
+				hits[c][i] = true
+			}
+		}
+	}
+	return bestSnippet(columns, tokens, hits)
+}
+
+// isTextSearch reports whether search looks for text rather than listing
+// all tasks or the tasks of a date.
+func isTextSearch(search string) bool {
+	_, err := time.Parse("02.01.2006", search)
+	return search != "" && err != nil
+}
+
+// saveWords indexes the words of the task's title and comment for the fuzzy
+// search, and drops the trigrams of its old words no task has any more.
+func saveWords(db querier, taskID int64, text string) error {
+	old, err := queryStrings(db, `SELECT word FROM task_words WHERE task_id = ?`, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to read task words: %w", err)
+	}
+	if _, err = db.Exec(`DELETE FROM task_words WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to delete task words: %w", err)
+	}
+
+	words := fuzzy.Words(text)
+	for _, word := range words {
+		if _, err = db.Exec(`INSERT INTO task_words (word, task_id) VALUES (?, ?)`, word, taskID); err != nil {
+			return fmt.Errorf("failed to save task words: %w", err)
+		}
+		for _, trigram := range fuzzy.Trigrams(word) {
+			query := `INSERT INTO word_trigrams (trigram, word) VALUES (?, ?) ON CONFLICT DO NOTHING`
+			if _, err = db.Exec(query, trigram, word); err != nil {
+				return fmt.Errorf("failed to save word trigrams: %w", err)
+			}
+		}
+	}
+
+	for _, word := range old {
+		if slices.Contains(words, word) {
+			continue
+		}
+		query := `DELETE FROM word_trigrams WHERE word = ? AND NOT EXISTS (SELECT 1 FROM task_words WHERE word = ?)`
+		if _, err = db.Exec(query, word, word); err != nil {
+			return fmt.Errorf("failed to delete word trigrams: %w", err)
+		}
+	}
+	return nil
+}
+
+func queryStrings(db querier, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (s SQLStore) GetFuzzyTasks(search, limit string) ([]Task, error) {
+	if !isTextSearch(search) {
+		return s.GetTasks(search, limit)
+	}
+
+	max, err := parseLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := fuzzySearch(search, s)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(matches)) > max {
+		matches = matches[:max]
+	}
+	if len(matches) == 0 {
+		return []Task{}, nil
+	}
+
+	ids := make([]any, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	query := selectTasks + `
+		WHERE scheduler.id IN (` + placeholders(len(ids)) + `)
+	`
+	found, err := s.queryTasks(query, false, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]Task, len(found))
+	for _, task := range found {
+		byID[task.ID] = task
+	}
+	tasks := make([]Task, 0, len(matches))
+	for _, m := range matches {
+		if task, ok := byID[m.id]; ok {
+			task.Snippet = fuzzySnippet(task, m.words)
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (s SQLStore) sharedTrigrams(trigrams []string) (map[string]int, error) {
+	query := `
+		SELECT word, COUNT(*)
+		FROM word_trigrams
+		WHERE trigram IN (` + placeholders(len(trigrams)) + `)
+		GROUP BY word
+	`
+	rows, err := s.conn.Query(query, anySlice(trigrams)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search trigrams: %w", err)
+	}
+	defer rows.Close()
+
+	shared := make(map[string]int)
+	for rows.Next() {
+		var word string
+		var count int
+		if err = rows.Scan(&word, &count); err != nil {
+			return nil, fmt.Errorf("failed to parse trigrams: %w", err)
+		}
+		shared[word] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate trigrams: %w", err)
+	}
+
+	return shared, nil
+}
+
+func (s SQLStore) wordTasks(words []string) (map[string][]int64, error) {
+	query := `
+		SELECT w.word, w.task_id
+		FROM task_words w
+		JOIN scheduler ON scheduler.id = w.task_id
+		WHERE w.word IN (` + placeholders(len(words)) + `)
+	`
+	rows, err := s.conn.Query(query, anySlice(words)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search words: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make(map[string][]int64)
+	for rows.Next() {
+		var word string
+		var id int64
+		if err = rows.Scan(&word, &id); err != nil {
+			return nil, fmt.Errorf("failed to parse words: %w", err)
+		}
+		tasks[word] = append(tasks[word], id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate words: %w", err)
+	}
+
+	return tasks, nil
+}
+
+func anySlice(values []string) []any {
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
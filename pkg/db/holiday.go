@@ -0,0 +1,243 @@
+package db
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_final_project/pkg/utils"
+)
+
+const (
+	holidayInputFormat = "02.01.2006"
+	icsCalendarBegin   = "BEGIN:VCALENDAR"
+	maxHolidaySpan     = 366
+)
+
+type Holiday struct {
+	Date  string `json:"date"`
+	Title string `json:"title"`
+}
+
+type HolidaysResp struct {
+	Holidays []Holiday `json:"holidays"`
+}
+
+func (s SQLStore) AddHolidays(holidays []Holiday) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO holidays (date, title) VALUES (?, ?)
+		ON CONFLICT (date) DO UPDATE SET title = excluded.title`
+	for _, holiday := range holidays {
+		if _, err = s.rebind(tx).Exec(query, holiday.Date, holiday.Title); err != nil {
+			return fmt.Errorf("failed to insert holiday: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit holidays: %w", err)
+	}
+	return nil
+}
+
+func (s SQLStore) GetHolidays() ([]Holiday, error) {
+	rows, err := s.conn.Query(`SELECT date, title FROM holidays ORDER BY date`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holidays: %w", err)
+	}
+	defer rows.Close()
+
+	holidays := []Holiday{}
+	for rows.Next() {
+		var holiday Holiday
+		if err = rows.Scan(&holiday.Date, &holiday.Title); err != nil {
+			return nil, fmt.Errorf("failed to parse holidays: %w", err)
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate holidays: %w", err)
+	}
+
+	return holidays, nil
+}
+
+func (s SQLStore) UpdateHoliday(holiday *Holiday) error {
+	res, err := s.conn.Exec(`UPDATE holidays SET title = ? WHERE date = ?`, holiday.Title, holiday.Date)
+	if err != nil {
+		return fmt.Errorf("failed to update holiday: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("holiday not found")
+	}
+
+	return nil
+}
+
+func (s SQLStore) DeleteHoliday(date string) error {
+	res, err := s.conn.Exec(`DELETE FROM holidays WHERE date = ?`, date)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("holiday not found")
+	}
+
+	return nil
+}
+
+func (s SQLStore) HolidayCalendar() (utils.HolidaySet, error) {
+	holidays, err := s.GetHolidays()
+	if err != nil {
+		return nil, err
+	}
+	return holidayCalendar(holidays), nil
+}
+
+func (holiday *Holiday) Validate() error {
+	date, err := parseHolidayDate(holiday.Date)
+	if err != nil {
+		return err
+	}
+	holiday.Date = date.Format(utils.DateFormat)
+	holiday.Title = strings.TrimSpace(holiday.Title)
+	return nil
+}
+
+func parseHolidayDate(value string) (time.Time, error) {
+	for _, layout := range []string{utils.DateFormat, holidayInputFormat} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid holiday date: " + value)
+}
+
+// ParseHolidays reads holidays from an iCalendar file or from a plain list
+// with one date per line, optionally followed by a title.
+func ParseHolidays(data string) ([]Holiday, error) {
+	if strings.HasPrefix(strings.TrimSpace(data), icsCalendarBegin) {
+		return parseICSHolidays(data)
+	}
+
+	var holidays []Holiday
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		date, title, _ := strings.Cut(text, " ")
+		holiday := Holiday{Date: date, Title: title}
+		if err := holiday.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read holidays: %w", err)
+	}
+	return holidays, nil
+}
+
+func parseICSHolidays(data string) ([]Holiday, error) {
+	var holidays []Holiday
+	var start, end time.Time
+	var title string
+	inEvent := false
+
+	for _, line := range unfoldICSLines(data) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent = true
+				start, end, title = time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			if len(value) < len(utils.DateFormat) {
+				return nil, errors.New("invalid event date: " + value)
+			}
+			date, err := time.Parse(utils.DateFormat, value[:len(utils.DateFormat)])
+			if err != nil {
+				return nil, errors.New("invalid event date: " + value)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				start = date
+			} else {
+				end = date
+			}
+		case "SUMMARY":
+			if inEvent {
+				title = unescapeICSText(value)
+			}
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, errors.New("event without DTSTART")
+			}
+
+			days := 1
+			if !end.IsZero() && end.After(start) {
+				days = int(end.Sub(start).Hours() / 24)
+			}
+			if days > maxHolidaySpan {
+				return nil, errors.New("event is too long: " + title)
+			}
+			for i := 0; i < days; i++ {
+				date := start.AddDate(0, 0, i).Format(utils.DateFormat)
+				holidays = append(holidays, Holiday{Date: date, Title: title})
+			}
+		}
+	}
+
+	return holidays, nil
+}
+
+func unfoldICSLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
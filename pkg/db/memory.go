@@ -0,0 +1,366 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go_final_project/pkg/fuzzy"
+	"go_final_project/pkg/utils"
+)
+
+// MemoryStore is a TaskStore that keeps everything in memory, for tests and
+// throwaway instances. It stores the same fields as SQLStore.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	nextID     int64
+	tasks      map[int64]Task
+	exceptions map[int64]map[string]bool
+	holidays   map[string]Holiday
+	// words indexes the tasks by their words, and trigrams those words by
+	// their trigrams.
+	words    map[string]map[int64]bool
+	trigrams map[string]map[string]bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:      make(map[int64]Task),
+		exceptions: make(map[int64]map[string]bool),
+		holidays:   make(map[string]Holiday),
+		words:      make(map[string]map[int64]bool),
+		trigrams:   make(map[string]map[string]bool),
+	}
+}
+
+// stored returns the task as SQLStore would read it back.
+func stored(task Task) Task {
+	task.RepeatText = ""
+	task.Snippet = ""
+	task.missed = nil
+	if task.Anchor == task.Date {
+		task.Anchor = ""
+	}
+	if !task.limited() {
+		task.Done = 0
+	}
+	return task
+}
+
+func (s *MemoryStore) AddTask(task *Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	task.ID = s.nextID
+	s.tasks[task.ID] = stored(*task)
+	s.index(*task, true)
+	return task.ID, nil
+}
+
+// index adds the words of the task to the word index, or removes them along
+// with the trigrams of the words no task has any more.
+func (s *MemoryStore) index(task Task, add bool) {
+	for _, word := range fuzzy.Words(task.Title + " " + task.Comment) {
+		if add {
+			if s.words[word] == nil {
+				s.words[word] = make(map[int64]bool)
+				for _, trigram := range fuzzy.Trigrams(word) {
+					if s.trigrams[trigram] == nil {
+						s.trigrams[trigram] = make(map[string]bool)
+					}
+					s.trigrams[trigram][word] = true
+				}
+			}
+			s.words[word][task.ID] = true
+			continue
+		}
+
+		delete(s.words[word], task.ID)
+		if len(s.words[word]) > 0 {
+			continue
+		}
+		delete(s.words, word)
+		for _, trigram := range fuzzy.Trigrams(word) {
+			delete(s.trigrams[trigram], word)
+			if len(s.trigrams[trigram]) == 0 {
+				delete(s.trigrams, trigram)
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) GetFuzzyTasks(search, limit string) ([]Task, error) {
+	if !isTextSearch(search) {
+		return s.GetTasks(search, limit)
+	}
+
+	max, err := parseLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := fuzzySearch(search, s)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []Task{}
+	for _, m := range matches {
+		if int64(len(tasks)) >= max {
+			break
+		}
+		if task, ok := s.tasks[m.id]; ok {
+			task.Snippet = fuzzySnippet(task, m.words)
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) sharedTrigrams(trigrams []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shared := make(map[string]int)
+	for _, trigram := range trigrams {
+		for word := range s.trigrams[trigram] {
+			shared[word]++
+		}
+	}
+	return shared, nil
+}
+
+func (s *MemoryStore) wordTasks(words []string) (map[string][]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make(map[string][]int64, len(words))
+	for _, word := range words {
+		for id := range s.words[word] {
+			tasks[word] = append(tasks[word], id)
+		}
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) GetTasks(search, limit string) ([]Task, error) {
+	max, err := parseLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// match reports whether a task is selected, with its score and snippet
+	// for a text search.
+	match := func(Task) (bool, int, string) { return true, 0, "" }
+	if search != "" {
+		if date, err := time.Parse("02.01.2006", search); err == nil {
+			day := date.Format(utils.DateFormat)
+			match = func(task Task) (bool, int, string) { return task.Date == day, 0, "" }
+		} else {
+			query, err := parseSearch(search)
+			if err != nil {
+				return nil, err
+			}
+			match = query.match
+		}
+	}
+
+	s.mu.RLock()
+	tasks := []Task{}
+	scores := make(map[int64]int)
+	for _, task := range s.tasks {
+		if ok, score, snippet := match(task); ok {
+			task.Snippet = snippet
+			scores[task.ID] = score
+			tasks = append(tasks, task)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		return a.ID < b.ID
+	})
+
+	if int64(len(tasks)) > max {
+		tasks = tasks[:max]
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) GetTask(id int64) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+	return &task, nil
+}
+
+func (s *MemoryStore) UpdateTask(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.tasks[task.ID]
+	if !ok {
+		return fmt.Errorf("task not found")
+	}
+	s.index(old, false)
+	s.tasks[task.ID] = stored(*task)
+	s.index(*task, true)
+	return nil
+}
+
+func (s *MemoryStore) DeleteTask(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return fmt.Errorf("task not found")
+	}
+	s.index(task, false)
+	delete(s.tasks, id)
+	delete(s.exceptions, id)
+	return nil
+}
+
+func (s *MemoryStore) AdvanceTask(task *Task, missed []Task, finished bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.tasks[task.ID]
+	if !ok {
+		return fmt.Errorf("task not found")
+	}
+	for i := range missed {
+		s.nextID++
+		missed[i].ID = s.nextID
+		s.tasks[missed[i].ID] = stored(missed[i])
+		s.index(missed[i], true)
+	}
+
+	s.index(old, false)
+	if finished {
+		delete(s.tasks, task.ID)
+		delete(s.exceptions, task.ID)
+		return nil
+	}
+	s.tasks[task.ID] = stored(*task)
+	s.index(*task, true)
+	return nil
+}
+
+func (s *MemoryStore) AddException(taskID int64, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exceptions[taskID] == nil {
+		s.exceptions[taskID] = make(map[string]bool)
+	}
+	s.exceptions[taskID][date] = true
+	return nil
+}
+
+func (s *MemoryStore) GetExceptions(taskID int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dates := []string{}
+	for date := range s.exceptions[taskID] {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+func (s *MemoryStore) DeleteException(taskID int64, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exceptions[taskID][date] {
+		return fmt.Errorf("exception not found")
+	}
+	delete(s.exceptions[taskID], date)
+	return nil
+}
+
+func (s *MemoryStore) ExceptionDates(taskID int64) ([]time.Time, error) {
+	dates, err := s.GetExceptions(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return exceptionDates(dates)
+}
+
+func (s *MemoryStore) AddHolidays(holidays []Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, holiday := range holidays {
+		s.holidays[holiday.Date] = holiday
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetHolidays() ([]Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	holidays := []Holiday{}
+	for _, holiday := range s.holidays {
+		holidays = append(holidays, holiday)
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays, nil
+}
+
+func (s *MemoryStore) UpdateHoliday(holiday *Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.holidays[holiday.Date]; !ok {
+		return fmt.Errorf("holiday not found")
+	}
+	s.holidays[holiday.Date] = *holiday
+	return nil
+}
+
+func (s *MemoryStore) DeleteHoliday(date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.holidays[date]; !ok {
+		return fmt.Errorf("holiday not found")
+	}
+	delete(s.holidays, date)
+	return nil
+}
+
+func (s *MemoryStore) HolidayCalendar() (utils.HolidaySet, error) {
+	holidays, err := s.GetHolidays()
+	if err != nil {
+		return nil, err
+	}
+	return holidayCalendar(holidays), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go_final_project/pkg/migrations"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresDialect searches the search column, which the migrations fill from
+// the title and comment with the simple configuration: words are only folded
+// to lower case, as in the SQLite index.
+var postgresDialect = &dialect{
+	migrations: migrations.Postgres,
+	bind:       sqlx.DOLLAR,
+	search: `SELECT` + taskColumns + `,
+	       ts_headline('simple', concat_ws(' ', scheduler.title, scheduler.comment), q,
+	                   'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=` + strconv.Itoa(snippetTokens) + `, MinWords=3')
+	FROM scheduler` + taskJoins + `
+	CROSS JOIN to_tsquery('simple', ?) q
+	WHERE scheduler.search @@ q
+	ORDER BY ts_rank(scheduler.search, q) DESC, scheduler.date, COALESCE(t.time, ''), scheduler.id
+	LIMIT ?`,
+	searchQuery: searchQuery.tsquery,
+}
+
+// PostgresDSN returns the PostgreSQL connection string from TODO_PG_DSN, or
+// an empty string when the tasks are kept in SQLite.
+func PostgresDSN() string {
+	return os.Getenv("TODO_PG_DSN")
+}
+
+// OpenPostgres connects to the PostgreSQL database at dsn without touching
+// its schema.
+func OpenPostgres(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error while open postgres: %w", err)
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error while connect to postgres: %w", err)
+	}
+	return db, nil
+}
+
+// InitPostgres connects to PostgreSQL and brings its schema up to date.
+// Several servers may do so at once.
+func InitPostgres(dsn string) (*sql.DB, error) {
+	db, err := OpenPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = postgresDialect.migrations.Up(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate db: %w", err)
+	}
+
+	return db, nil
+}
+
+func NewPostgresStore(db *sql.DB) SQLStore {
+	return newSQLStore(db, postgresDialect)
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"fmt"
+
+	"go_final_project/pkg/utils"
+)
+
+// Review returns the spaced-repetition schedule of the task.
+func (task *Task) Review() utils.Review {
+	return utils.Review{Ease: task.Ease, Interval: int(task.Interval), Reps: int(task.Reps)}
+}
+
+func (task *Task) SetReview(review utils.Review) {
+	task.Ease, task.Interval, task.Reps = review.Ease, int64(review.Interval), int64(review.Reps)
+}
+
+func saveReview(db execer, task *Task) error {
+	if task.Review() == (utils.Review{}) {
+		if _, err := db.Exec(`DELETE FROM task_review WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("failed to delete review schedule: %w", err)
+		}
+		return nil
+	}
+
+	query := `INSERT INTO task_review (task_id, ease, interval, reps) VALUES (?, ?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET ease = excluded.ease, interval = excluded.interval, reps = excluded.reps`
+	if _, err := db.Exec(query, task.ID, task.Ease, task.Interval, task.Reps); err != nil {
+		return fmt.Errorf("failed to save review schedule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go_final_project/pkg/fuzzy"
+)
+
+const (
+	snippetOpen     = "\x02"
+	snippetClose    = "\x03"
+	snippetTokens   = 10
+	snippetEllipsis = "…"
+)
+
+var ErrInvalidSearch = errors.New("search needs a word that is not excluded")
+
+// searchTerm is a word, a phrase or a prefix to look for in the title or
+// comment of a task, or to exclude. Words are folded to lower case.
+type searchTerm struct {
+	words  []string
+	prefix bool
+	not    bool
+}
+
+// searchQuery matches the tasks that match every term of at least one of its
+// clauses.
+type searchQuery [][]searchTerm
+
+// parseSearch reads a search in the web search style: words and "quoted
+// phrases" must all be present, a trailing * makes a prefix, a leading - or
+// NOT excludes a term, and OR separates alternatives.
+func parseSearch(search string) (searchQuery, error) {
+	var query searchQuery
+	var clause []searchTerm
+	not := false
+
+	endClause := func() error {
+		if len(clause) == 0 {
+			return nil
+		}
+		positive := false
+		for _, term := range clause {
+			positive = positive || !term.not
+		}
+		if !positive {
+			return ErrInvalidSearch
+		}
+		query = append(query, clause)
+		clause = nil
+		return nil
+	}
+
+	runes := []rune(search)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var term searchTerm
+		if runes[i] == '-' {
+			term.not = true
+			i++
+		}
+
+		var raw string
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			raw = string(runes[i+1 : end])
+			i = min(end+1, len(runes))
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			raw = string(runes[i:end])
+			i = end
+
+			switch raw {
+			case "OR":
+				if err := endClause(); err != nil {
+					return nil, err
+				}
+				not = false
+				continue
+			case "AND":
+				continue
+			case "NOT":
+				not = true
+				continue
+			}
+		}
+
+		if i < len(runes) && runes[i] == '*' {
+			term.prefix = true
+			i++
+		} else if strings.HasSuffix(raw, "*") {
+			term.prefix = true
+		}
+
+		for _, token := range tokenize(raw) {
+			term.words = append(term.words, token.word)
+		}
+		term.not = term.not || not
+		not = false
+		if len(term.words) > 0 {
+			clause = append(clause, term)
+		}
+	}
+
+	if err := endClause(); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// fts5 renders the query in the FTS5 MATCH syntax.
+func (q searchQuery) fts5() string {
+	clauses := make([]string, len(q))
+	for i, clause := range q {
+		var positive, negative []string
+		for _, term := range clause {
+			phrase := `"` + strings.Join(term.words, " ") + `"`
+			if term.prefix {
+				phrase += " *"
+			}
+			if term.not {
+				negative = append(negative, phrase)
+			} else {
+				positive = append(positive, phrase)
+			}
+		}
+		clauses[i] = "(" + strings.Join(positive, " AND ")
+		for _, phrase := range negative {
+			clauses[i] += " NOT " + phrase
+		}
+		clauses[i] += ")"
+	}
+	return strings.Join(clauses, " OR ")
+}
+
+// tsquery renders the query as a PostgreSQL tsquery.
+func (q searchQuery) tsquery() string {
+	clauses := make([]string, len(q))
+	for i, clause := range q {
+		terms := make([]string, len(clause))
+		for j, term := range clause {
+			lexemes := make([]string, len(term.words))
+			for k, word := range term.words {
+				lexemes[k] = "'" + word + "'"
+			}
+			if term.prefix {
+				lexemes[len(lexemes)-1] += ":*"
+			}
+			terms[j] = "(" + strings.Join(lexemes, " <-> ") + ")"
+			if term.not {
+				terms[j] = "!" + terms[j]
+			}
+		}
+		clauses[i] = "(" + strings.Join(terms, " & ") + ")"
+	}
+	return strings.Join(clauses, " | ")
+}
+
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits s into words of letters, digits and marks folded to lower
+// case, as the unicode61 tokenizer of FTS5 does.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s + " " {
+		inWord := fuzzy.IsWordRune(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, token{word: strings.ToLower(s[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// hits returns the indexes of the tokens the term matches.
+func (term searchTerm) hits(tokens []token) []int {
+	var hits []int
+	n := len(term.words)
+	for i := 0; i+n <= len(tokens); i++ {
+		match := true
+		for k, word := range term.words {
+			got := tokens[i+k].word
+			if k == n-1 && term.prefix {
+				match = strings.HasPrefix(got, word)
+			} else {
+				match = got == word
+			}
+			if !match {
+				break
+			}
+		}
+		if match {
+			for k := 0; k < n; k++ {
+				hits = append(hits, i+k)
+			}
+		}
+	}
+	return hits
+}
+
+// match reports whether the task matches the query, with a score that grows
+// with the number of matched words and a highlighted snippet.
+func (q searchQuery) match(task Task) (bool, int, string) {
+	columns := []string{task.Title, task.Comment}
+	tokens := [][]token{tokenize(task.Title), tokenize(task.Comment)}
+
+	for _, clause := range q {
+		score := 0
+		hits := make([]map[int]bool, len(columns))
+		matched := true
+		for _, term := range clause {
+			found := false
+			for c := range columns {
+				termHits := term.hits(tokens[c])
+				if len(termHits) == 0 {
+					continue
+				}
+				found = true
+				if term.not {
+					break
+				}
+				if hits[c] == nil {
+					hits[c] = make(map[int]bool)
+				}
+				for _, hit := range termHits {
+					hits[c][hit] = true
+				}
+				score += len(termHits)
+			}
+			if found == term.not {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		return true, score, bestSnippet(columns, tokens, hits)
+	}
+	return false, 0, ""
+}
+
+// bestSnippet makes the snippet of the column with the most hits, the first
+// of them on a tie.
+func bestSnippet(columns []string, tokens [][]token, hits []map[int]bool) string {
+	best := 0
+	for c := range columns {
+		if len(hits[c]) > len(hits[best]) {
+			best = c
+		}
+	}
+	return snippet(columns[best], tokens[best], hits[best])
+}
+
+// snippet marks the hits in text and cuts it down to snippetTokens tokens
+// starting at the first hit.
+func snippet(text string, tokens []token, hits map[int]bool) string {
+	first, last := 0, len(tokens)
+	if len(tokens) > snippetTokens {
+		indexes := make([]int, 0, len(hits))
+		for i := range hits {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		if len(indexes) > 0 {
+			first = min(indexes[0], len(tokens)-snippetTokens)
+		}
+		last = first + snippetTokens
+	}
+	if first >= last {
+		return ""
+	}
+
+	var b strings.Builder
+	if first > 0 {
+		b.WriteString(snippetEllipsis)
+	} else {
+		b.WriteString(text[:tokens[0].start])
+	}
+	for i := first; i < last; i++ {
+		if i > first {
+			b.WriteString(text[tokens[i-1].end:tokens[i].start])
+		}
+		if hits[i] {
+			b.WriteString(snippetOpen + text[tokens[i].start:tokens[i].end] + snippetClose)
+		} else {
+			b.WriteString(text[tokens[i].start:tokens[i].end])
+		}
+	}
+	if last < len(tokens) {
+		b.WriteString(snippetEllipsis)
+	} else {
+		b.WriteString(text[tokens[last-1].end:])
+	}
+	return highlight(b.String())
+}
+
+// highlight escapes a snippet for HTML and turns its markers into <mark>.
+func highlight(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, snippetOpen, "<mark>")
+	return strings.ReplaceAll(escaped, snippetClose, "</mark>")
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go_final_project/pkg/migrations"
+	"go_final_project/pkg/utils"
+)
+
+const (
+	StorageSQLite   = "sqlite"
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// TaskStore keeps tasks together with their exception dates and the holiday
+// calendar. Implementations must be safe for concurrent use.
+type TaskStore interface {
+	AddTask(task *Task) (int64, error)
+	// GetTasks returns up to limit tasks, all of them for a negative limit.
+	// Without a search they are ordered by date and time, and a search that
+	// is a valid date in the 02.01.2006 format selects the tasks of that
+	// date. Any other search, a date-shaped one such as 31.02.2024 included,
+	// is a full-text query in the parseSearch syntax over title and comment:
+	// an FTS5 MATCH on SQLite, a tsquery on PostgreSQL and the same matching
+	// in memory. Its results come best ranked first, then by date and time,
+	// each with a snippet of the matches. A search that excludes every word
+	// fails with ErrInvalidSearch.
+	GetTasks(search, limit string) ([]Task, error)
+	// GetFuzzyTasks is GetTasks for a search with typos: it returns the
+	// tasks with a word similar to every word of search, most similar first.
+	GetFuzzyTasks(search, limit string) ([]Task, error)
+	GetTask(id int64) (*Task, error)
+	UpdateTask(task *Task) error
+	DeleteTask(id int64) error
+	// AdvanceTask saves a task moved on by Task.Advance together with the
+	// one-off tasks for the occurrences it missed, all or nothing. A finished
+	// task, which has no occurrences left, is deleted instead.
+	AdvanceTask(task *Task, missed []Task, finished bool) error
+
+	AddException(taskID int64, date string) error
+	GetExceptions(taskID int64) ([]string, error)
+	DeleteException(taskID int64, date string) error
+	ExceptionDates(taskID int64) ([]time.Time, error)
+
+	AddHolidays(holidays []Holiday) error
+	GetHolidays() ([]Holiday, error)
+	UpdateHoliday(holiday *Holiday) error
+	DeleteHoliday(date string) error
+	HolidayCalendar() (utils.HolidaySet, error)
+
+	Close() error
+}
+
+// NewStore opens the storage chosen by TODO_STORAGE: the SQLite database by
+// default, PostgreSQL at TODO_PG_DSN, which is also the default once the DSN
+// is set, or memory for a store that lives as long as the process.
+func NewStore() (TaskStore, error) {
+	switch storage := storageKind(); storage {
+	case "", StorageSQLite:
+		db, err := Init()
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLiteStore(db), nil
+	case StoragePostgres:
+		if PostgresDSN() == "" {
+			return nil, errors.New("TODO_PG_DSN is required for postgres storage")
+		}
+		db, err := InitPostgres(PostgresDSN())
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(db), nil
+	case StorageMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, errors.New("unknown storage: " + storage)
+	}
+}
+
+// OpenSchema opens the SQL database NewStore would use, without touching its
+// schema, together with the migrations for it.
+func OpenSchema() (*sql.DB, *migrations.Dialect, error) {
+	switch storage := storageKind(); storage {
+	case "", StorageSQLite:
+		db, err := Open()
+		return db, sqliteDialect.migrations, err
+	case StoragePostgres:
+		db, err := OpenPostgres(PostgresDSN())
+		return db, postgresDialect.migrations, err
+	default:
+		return nil, nil, errors.New("storage has no schema: " + storage)
+	}
+}
+
+func storageKind() string {
+	storage := os.Getenv("TODO_STORAGE")
+	if storage == "" && PostgresDSN() != "" {
+		return StoragePostgres
+	}
+	return storage
+}
+
+// parseLimit reads the limit of GetTasks, where a negative one means none.
+func parseLimit(limit string) (int64, error) {
+	max, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch tasks: invalid limit %q", limit)
+	}
+	if max < 0 {
+		max = math.MaxInt64
+	}
+	return max, nil
+}
+
+func exceptionDates(dates []string) ([]time.Time, error) {
+	result := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		parsed, err := time.Parse(utils.DateFormat, date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exception date %q: %w", date, err)
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func holidayCalendar(holidays []Holiday) utils.HolidaySet {
+	calendar := make(utils.HolidaySet, len(holidays))
+	for _, holiday := range holidays {
+		calendar[holiday.Date] = true
+	}
+	return calendar
+}
@@ -0,0 +1,528 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"go_final_project/pkg/migrations"
+)
+
+// testStore is the conformance suite every TaskStore must pass. newStore
+// returns an empty store.
+func testStore(t *testing.T, newStore func(t *testing.T) TaskStore) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		store := newStore(t)
+		task := Task{
+			Date: "20240126", Title: "Зарядка", Comment: "утром", Repeat: "d 1",
+			Time: "07:30", TZ: "Europe/Moscow", Until: "20241231", Count: 10, Done: 2,
+			CatchUp: CatchUpEach, Anchor: "20240119", Ease: 2.6, Interval: 6, Reps: 2,
+			RepeatText: "каждый день",
+		}
+		id, err := store.AddTask(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == 0 || task.ID != id {
+			t.Fatalf("AddTask returned id %d, task has %d", id, task.ID)
+		}
+
+		got, err := store.GetTask(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := task
+		want.RepeatText = ""
+		if !reflect.DeepEqual(*got, want) {
+			t.Fatalf("GetTask = %+v, want %+v", *got, want)
+		}
+
+		got.Until, got.Count, got.Done, got.Time, got.TZ, got.CatchUp, got.Anchor = "", 0, 5, "", "", "", ""
+		got.Ease, got.Interval, got.Reps = 0, 0, 0
+		if err = store.UpdateTask(got); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := store.GetTask(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = Task{ID: id, Date: "20240126", Title: "Зарядка", Comment: "утром", Repeat: "d 1"}
+		if !reflect.DeepEqual(*updated, want) {
+			t.Fatalf("after update GetTask = %+v, want %+v", *updated, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.GetTask(42); err == nil {
+			t.Error("GetTask of a missing task succeeded")
+		}
+		if err := store.UpdateTask(&Task{ID: 42, Date: "20240126", Title: "x"}); err == nil {
+			t.Error("UpdateTask of a missing task succeeded")
+		}
+		if err := store.DeleteTask(42); err == nil {
+			t.Error("DeleteTask of a missing task succeeded")
+		}
+		if err := store.DeleteException(42, "20240126"); err == nil {
+			t.Error("DeleteException of a missing date succeeded")
+		}
+		if err := store.UpdateHoliday(&Holiday{Date: "20240101"}); err == nil {
+			t.Error("UpdateHoliday of a missing holiday succeeded")
+		}
+		if err := store.DeleteHoliday("20240101"); err == nil {
+			t.Error("DeleteHoliday of a missing holiday succeeded")
+		}
+	})
+
+	t.Run("GetTasks", func(t *testing.T) {
+		store := newStore(t)
+		tasks := []Task{
+			{Date: "20240128", Title: "Купить молоко"},
+			{Date: "20240126", Title: "Report", Comment: "quarterly REPORT", Time: "18:00"},
+			{Date: "20240126", Title: "Позвонить маме", Time: "09:00"},
+			{Date: "20240127", Title: "Оплатить счета", Comment: "до вечера"},
+		}
+		for i := range tasks {
+			if _, err := store.AddTask(&tasks[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, c := range []struct {
+			search, limit string
+			want          []string
+		}{
+			{"", "50", []string{"Позвонить маме", "Report", "Оплатить счета", "Купить молоко"}},
+			{"", "2", []string{"Позвонить маме", "Report"}},
+			{"26.01.2024", "50", []string{"Позвонить маме", "Report"}},
+			{"report", "50", []string{"Report"}},
+			{"вечера", "50", []string{"Оплатить счета"}},
+			{"молоко", "50", []string{"Купить молоко"}},
+			{"МОЛОКО", "50", []string{"Купить молоко"}},
+			{"мол*", "50", []string{"Купить молоко"}},
+			{"олок", "50", []string{}},
+			{`"купить молоко"`, "50", []string{"Купить молоко"}},
+			{`"молоко купить"`, "50", []string{}},
+			{"купить -молоко", "50", []string{}},
+			{"маме NOT молоко", "50", []string{"Позвонить маме"}},
+			{"молоко OR маме", "50", []string{"Купить молоко", "Позвонить маме"}},
+			{"нет такого", "50", []string{}},
+			{"!!!", "50", []string{}},
+		} {
+			got, err := store.GetTasks(c.search, c.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			titles := []string{}
+			for _, task := range got {
+				titles = append(titles, task.Title)
+			}
+			if strings.Contains(c.search, " OR ") {
+				sort.Strings(titles)
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(c.want) {
+				t.Errorf("GetTasks(%q, %q) = %q, want %q", c.search, c.limit, titles, c.want)
+			}
+		}
+	})
+
+	t.Run("FullTextSearch", func(t *testing.T) {
+		store := newStore(t)
+		tasks := []Task{
+			{Date: "20240126", Title: "Полить цветы"},
+			{Date: "20240127", Title: "Цветы", Comment: "купить цветы, цветы для мамы"},
+			{Date: "20240128", Title: "<b>Сыр</b> & хлеб"},
+		}
+		for i := range tasks {
+			if _, err := store.AddTask(&tasks[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := store.GetTasks("цветы", "50")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0].ID != tasks[1].ID {
+			t.Fatalf("GetTasks(цветы) = %+v, want the task with more matches first", got)
+		}
+		if got[1].Snippet != "Полить <mark>цветы</mark>" {
+			t.Errorf("snippet = %q", got[1].Snippet)
+		}
+
+		got, err = store.GetTasks("сыр", "50")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Snippet != "&lt;b&gt;<mark>Сыр</mark>&lt;/b&gt; &amp; хлеб" {
+			t.Fatalf("GetTasks(сыр) = %+v", got)
+		}
+
+		if _, err = store.GetTasks("-сыр", "50"); !errors.Is(err, ErrInvalidSearch) {
+			t.Errorf("GetTasks(-сыр) error = %v, want ErrInvalidSearch", err)
+		}
+
+		tasks[2].Title = "Купить творог"
+		if err = store.UpdateTask(&tasks[2]); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = store.GetTasks("сыр", "50"); len(got) != 0 {
+			t.Errorf("the old title is still found: %+v", got)
+		}
+		if got, _ = store.GetTasks("творог", "50"); len(got) != 1 {
+			t.Errorf("the new title is not found: %+v", got)
+		}
+
+		if err = store.DeleteTask(tasks[2].ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = store.GetTasks("творог", "50"); len(got) != 0 {
+			t.Errorf("a deleted task is found: %+v", got)
+		}
+	})
+
+	t.Run("FuzzySearch", func(t *testing.T) {
+		store := newStore(t)
+		tasks := []Task{
+			{Date: "20240126", Title: "Посмотреть фильм"},
+			{Date: "20240127", Title: "Купить молоко"},
+			{Date: "20240128", Title: "Фильмотека"},
+		}
+		for i := range tasks {
+			if _, err := store.AddTask(&tasks[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, c := range []struct {
+			search string
+			want   []string
+		}{
+			{"фильи", []string{"Посмотреть фильм", "Фильмотека"}},
+			{"посмотерть филм", []string{"Посмотреть фильм"}},
+			{"МОЛКО", []string{"Купить молоко"}},
+			{"qwerty", []string{}},
+			{"27.01.2024", []string{"Купить молоко"}},
+		} {
+			got, err := store.GetFuzzyTasks(c.search, "50")
+			if err != nil {
+				t.Fatal(err)
+			}
+			titles := []string{}
+			for _, task := range got {
+				titles = append(titles, task.Title)
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(c.want) {
+				t.Errorf("GetFuzzyTasks(%q) = %q, want %q", c.search, titles, c.want)
+			}
+		}
+
+		got, err := store.GetFuzzyTasks("филм", "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Snippet != "Посмотреть <mark>фильм</mark>" {
+			t.Fatalf("GetFuzzyTasks(филм, 1) = %+v", got)
+		}
+
+		tasks[1].Title = "Купить кефир"
+		if err = store.UpdateTask(&tasks[1]); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = store.GetFuzzyTasks("молко", "50"); len(got) != 0 {
+			t.Errorf("the old title is still found: %+v", got)
+		}
+		if got, _ = store.GetFuzzyTasks("кифир", "50"); len(got) != 1 {
+			t.Errorf("the new title is not found: %+v", got)
+		}
+
+		if err = store.DeleteTask(tasks[1].ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = store.GetFuzzyTasks("кифир", "50"); len(got) != 0 {
+			t.Errorf("a deleted task is found: %+v", got)
+		}
+	})
+
+	t.Run("BusinessDayAnchor", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.AddHolidays([]Holiday{{Date: "20240108", Title: "Рождество"}}); err != nil {
+			t.Fatal(err)
+		}
+		task := Task{Date: "20240101", Title: "Планёрка", Repeat: "d 7 next-bd"}
+		id, err := store.AddTask(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []string{"20240109", "20240115", "20240122"} {
+			task, err := store.GetTask(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			calendar, err := store.HolidayCalendar()
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule, err := task.Rule(calendar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, err := task.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = task.Advance(rule, start, true); err != nil {
+				t.Fatal(err)
+			}
+			if task.Date != want {
+				t.Fatalf("done on %s moved the task to %s, want %s", start.Format("20060102"), task.Date, want)
+			}
+			if err = store.UpdateTask(task); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("Exceptions", func(t *testing.T) {
+		store := newStore(t)
+		task := Task{Date: "20240126", Title: "Бассейн", Repeat: "w 5"}
+		if _, err := store.AddTask(&task); err != nil {
+			t.Fatal(err)
+		}
+		for _, date := range []string{"20240209", "20240202", "20240209"} {
+			if err := store.AddException(task.ID, date); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		dates, err := store.GetExceptions(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(dates) != "[20240202 20240209]" {
+			t.Fatalf("GetExceptions = %q", dates)
+		}
+		parsed, err := store.ExceptionDates(task.ID)
+		if err != nil || len(parsed) != 2 || parsed[0].Day() != 2 {
+			t.Fatalf("ExceptionDates = %v, %v", parsed, err)
+		}
+
+		if err = store.DeleteException(task.ID, "20240202"); err != nil {
+			t.Fatal(err)
+		}
+		if err = store.DeleteTask(task.ID); err != nil {
+			t.Fatal(err)
+		}
+		if dates, _ = store.GetExceptions(task.ID); len(dates) != 0 {
+			t.Fatalf("exceptions of a deleted task: %q", dates)
+		}
+	})
+
+	t.Run("AdvanceTask", func(t *testing.T) {
+		store := newStore(t)
+		task := Task{Date: "20240120", Title: "Подкормка", Repeat: "d 3", CatchUp: CatchUpEach}
+		if _, err := store.AddTask(&task); err != nil {
+			t.Fatal(err)
+		}
+
+		task.Date = "20240129"
+		missed := []Task{{Date: "20240123", Title: "Подкормка"}, {Date: "20240126", Title: "Подкормка"}}
+		if err := store.AdvanceTask(&task, missed, false); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetTasks("", "50")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dates []string
+		for _, task := range got {
+			dates = append(dates, task.Date+" "+task.Repeat)
+		}
+		if fmt.Sprint(dates) != "[20240123  20240126  20240129 d 3]" {
+			t.Fatalf("tasks after advancing: %q", dates)
+		}
+
+		// Nothing is kept when the task is gone.
+		gone := Task{ID: task.ID + 100, Date: "20240201", Title: "Пропавшая"}
+		if err = store.AdvanceTask(&gone, []Task{{Date: "20240130", Title: "Пропавшая"}}, false); err == nil {
+			t.Error("AdvanceTask of a missing task succeeded")
+		}
+		if got, _ = store.GetTasks("", "50"); len(got) != 3 {
+			t.Errorf("got %d tasks after a failed advance, want 3", len(got))
+		}
+
+		if err = store.AdvanceTask(&task, []Task{{Date: "20240129", Title: "Подкормка"}}, true); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.GetTask(task.ID); err == nil {
+			t.Error("a finished task is still stored")
+		}
+		if got, _ = store.GetTasks("", "50"); len(got) != 3 {
+			t.Errorf("got %d tasks after finishing, want 3", len(got))
+		}
+	})
+
+	t.Run("Holidays", func(t *testing.T) {
+		store := newStore(t)
+		err := store.AddHolidays([]Holiday{{Date: "20240108", Title: "Рождество"}, {Date: "20240101", Title: "Новый год"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = store.AddHolidays([]Holiday{{Date: "20240108", Title: "Каникулы"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err = store.UpdateHoliday(&Holiday{Date: "20240101", Title: "1 января"}); err != nil {
+			t.Fatal(err)
+		}
+
+		holidays, err := store.GetHolidays()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(holidays) != "[{20240101 1 января} {20240108 Каникулы}]" {
+			t.Fatalf("GetHolidays = %v", holidays)
+		}
+
+		if err = store.DeleteHoliday("20240108"); err != nil {
+			t.Fatal(err)
+		}
+		calendar, err := store.HolidayCalendar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(calendar) != 1 || !calendar["20240101"] {
+			t.Fatalf("HolidayCalendar = %v", calendar)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		store := newStore(t)
+		const workers, perWorker = 8, 10
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					task := Task{Date: "20240126", Title: fmt.Sprintf("task %d-%d", w, i)}
+					if _, err := store.AddTask(&task); err != nil {
+						errs <- err
+						return
+					}
+					task.Comment = "updated"
+					if err := store.UpdateTask(&task); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatal(err)
+		}
+
+		tasks, err := store.GetTasks("updated", "1000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make(map[int64]bool)
+		for _, task := range tasks {
+			ids[task.ID] = true
+		}
+		if len(ids) != workers*perWorker {
+			t.Fatalf("got %d distinct tasks, want %d", len(ids), workers*perWorker)
+		}
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) TaskStore {
+		t.Setenv("TODO_DBFILE", filepath.Join(t.TempDir(), "scheduler.db"))
+		db, err := Init()
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := NewSQLiteStore(db)
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestSQLiteDeleteCascades(t *testing.T) {
+	t.Setenv("TODO_DBFILE", filepath.Join(t.TempDir(), "scheduler.db"))
+	db, err := Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewSQLiteStore(db)
+	defer store.Close()
+
+	task := Task{
+		Date: "20240126", Title: "Зарядка", Comment: "утром", Repeat: "d 1",
+		Time: "07:30", TZ: "Europe/Moscow", Until: "20241231", Count: 10, Done: 2,
+		CatchUp: CatchUpEach, Anchor: "20240119", Ease: 2.6, Interval: 6, Reps: 2,
+	}
+	if _, err = store.AddTask(&task); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.AddException(task.ID, "20240201"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.DeleteTask(task.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []string{"task_exceptions", "task_recurrence", "task_times", "task_catchup",
+		"task_anchor", "task_review", "task_words", "word_trigrams"}
+	for _, table := range tables {
+		var count int
+		if err = db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s keeps %d rows of the deleted task", table, count)
+		}
+	}
+}
+
+// TestPostgresStore runs against the database at PG_TEST_DSN, which it
+// wipes, and is skipped unless one is given.
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
+	testStore(t, func(t *testing.T) TaskStore {
+		db, err := OpenPostgres(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if err = migrations.Postgres.DownTo(db, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err = migrations.Postgres.Up(db); err != nil {
+			t.Fatal(err)
+		}
+		return NewPostgresStore(db)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) TaskStore {
+		return NewMemoryStore()
+	})
+}
@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"go_final_project/pkg/utils"
+
+	"github.com/jmoiron/sqlx"
 )
 
 type Task struct {
@@ -15,74 +19,204 @@ type Task struct {
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	Time    string `json:"time,omitempty"`
+	TZ      string `json:"tz,omitempty"`
+	Until   string `json:"until,omitempty"`
+	Count   int64  `json:"count,omitempty,string"`
+	Done    int64  `json:"done,omitempty,string"`
+	CatchUp string `json:"catchup,omitempty"`
+	// Anchor is the date the repeat rule counts from once the task has moved
+	// on from it, so that occurrences shifted to a business day do not shift
+	// the ones after them. It is empty while that is the task's own date.
+	Anchor string `json:"-"`
+
+	Ease     float64 `json:"ease,omitempty"`
+	Interval int64   `json:"interval,omitempty,string"`
+	Reps     int64   `json:"reps,omitempty,string"`
+
+	RepeatText string `json:"repeat_text,omitempty"`
+	// Snippet is the part of the title or comment that matched a text
+	// search, HTML-escaped, with the matched words in <mark>.
+	Snippet string `json:"snippet,omitempty"`
+
+	missed []Task
 }
 
 type TasksResp struct {
 	Tasks []Task `json:"tasks"`
 }
 
-type Storage struct {
-	db *sql.DB
+var (
+	defaultLocation     *time.Location
+	defaultLocationOnce sync.Once
+)
+
+// SQLStore is a TaskStore kept in a SQL database: the SQLite file by
+// default, or PostgreSQL.
+type SQLStore struct {
+	db      *sql.DB
+	conn    rebound
+	dialect *dialect
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+type querier interface {
+	execer
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
-func NewStorage(db *sql.DB) Storage {
-	return Storage{db: db}
+// rebound runs queries written with ? placeholders in the placeholder style
+// of the database.
+type rebound struct {
+	querier
+	bind int
 }
 
-func (s Storage) AddTask(task *Task) (int64, error) {
-	var id int64
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := s.db.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
+func (r rebound) Exec(query string, args ...any) (sql.Result, error) {
+	return r.querier.Exec(sqlx.Rebind(r.bind, query), args...)
+}
+
+func (r rebound) Query(query string, args ...any) (*sql.Rows, error) {
+	return r.querier.Query(sqlx.Rebind(r.bind, query), args...)
+}
+
+func (r rebound) QueryRow(query string, args ...any) *sql.Row {
+	return r.querier.QueryRow(sqlx.Rebind(r.bind, query), args...)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const (
+	taskColumns = `
+	       scheduler.id, scheduler.date, scheduler.title, scheduler.comment, scheduler.repeat,
+	       COALESCE(t.time, ''), COALESCE(t.tz, ''),
+	       COALESCE(r.until, ''), COALESCE(r.count, 0), COALESCE(r.done, 0),
+	       COALESCE(c.policy, ''), COALESCE(a.date, ''),
+	       COALESCE(v.ease, 0), COALESCE(v.interval, 0), COALESCE(v.reps, 0)`
+
+	taskJoins = `
+	LEFT JOIN task_times t ON t.task_id = scheduler.id
+	LEFT JOIN task_recurrence r ON r.task_id = scheduler.id
+	LEFT JOIN task_catchup c ON c.task_id = scheduler.id
+	LEFT JOIN task_anchor a ON a.task_id = scheduler.id
+	LEFT JOIN task_review v ON v.task_id = scheduler.id`
+
+	selectTasks = `SELECT` + taskColumns + `
+	FROM scheduler` + taskJoins + `
+`
+)
+
+func newSQLStore(db *sql.DB, d *dialect) SQLStore {
+	return SQLStore{db: db, conn: rebound{db, d.bind}, dialect: d}
+}
+
+func NewSQLiteStore(db *sql.DB) SQLStore {
+	return newSQLStore(db, sqliteDialect)
+}
+
+func (s SQLStore) rebind(tx *sql.Tx) rebound {
+	return rebound{tx, s.dialect.bind}
+}
+
+func (s SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func (s SQLStore) AddTask(task *Task) (int64, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("failed to insert task: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = insertTask(s.rebind(tx), task); err != nil {
+		return 0, err
 	}
 
-	id, err = res.LastInsertId()
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit task: %w", err)
+	}
+
+	return task.ID, nil
+}
+
+func insertTask(conn querier, task *Task) error {
+	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?) RETURNING id`
+	err := conn.QueryRow(query, task.Date, task.Title, task.Comment, task.Repeat).Scan(&task.ID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get LastInsertId: %w", err)
+		return fmt.Errorf("failed to insert task: %w", err)
+	}
+	return saveDetails(conn, task)
+}
+
+// saveDetails saves what a task keeps outside the scheduler table.
+func saveDetails(conn querier, task *Task) error {
+	if err := saveRecurrence(conn, task); err != nil {
+		return err
+	}
+
+	if err := saveTime(conn, task); err != nil {
+		return err
+	}
+
+	if err := saveCatchUp(conn, task); err != nil {
+		return err
+	}
+
+	if err := saveAnchor(conn, task); err != nil {
+		return err
 	}
-	task.ID = id
 
-	return id, nil
+	if err := saveReview(conn, task); err != nil {
+		return err
+	}
+
+	return saveWords(conn, task.ID, task.Title+" "+task.Comment)
 }
 
-func (s Storage) GetTasks(search, limit string) ([]Task, error) {
-	var query string
-	var args []interface{}
+func (s SQLStore) GetTasks(search, limit string) ([]Task, error) {
+	max, err := parseLimit(limit)
+	if err != nil {
+		return nil, err
+	}
 
-	if search != "" {
-		parsedDate, err := time.Parse("02.01.2006", search)
-		if err == nil {
-			query = `
-				SELECT id, date, title, comment, repeat
-				FROM scheduler
-				WHERE date = ?
-				ORDER BY date
-				LIMIT ?
-			`
-			args = append(args, parsedDate.Format(utils.DateFormat), limit)
-		} else {
-			searchPattern := "%" + search + "%"
-			query = `
-				SELECT id, date, title, comment, repeat
-				FROM scheduler
-				WHERE title LIKE ? OR comment LIKE ?
-				ORDER BY date
-				LIMIT ?
-			`
-			args = append(args, searchPattern, searchPattern, limit)
-		}
-	} else {
-		query = `
-			SELECT id, date, title, comment, repeat
-			FROM scheduler
-			ORDER BY date
+	if search == "" {
+		query := selectTasks + `
+			ORDER BY scheduler.date, COALESCE(t.time, ''), scheduler.id
 			LIMIT ?
 		`
-		args = append(args, limit)
+		return s.queryTasks(query, false, max)
+	}
+
+	if parsedDate, err := time.Parse("02.01.2006", search); err == nil {
+		query := selectTasks + `
+			WHERE scheduler.date = ?
+			ORDER BY scheduler.date, COALESCE(t.time, ''), scheduler.id
+			LIMIT ?
+		`
+		return s.queryTasks(query, false, parsedDate.Format(utils.DateFormat), max)
+	}
+
+	query, err := parseSearch(search)
+	if err != nil {
+		return nil, err
 	}
+	if len(query) == 0 {
+		return []Task{}, nil
+	}
+	return s.queryTasks(s.dialect.search, true, s.dialect.searchQuery(query), max)
+}
 
-	rows, err := s.db.Query(query, args...)
+// queryTasks reads the tasks selected by query, each followed by a raw
+// snippet if withSnippet is set.
+func (s SQLStore) queryTasks(query string, withSnippet bool, args ...any) ([]Task, error) {
+	rows, err := s.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch tasks: %w", err)
 	}
@@ -91,9 +225,15 @@ func (s Storage) GetTasks(search, limit string) ([]Task, error) {
 	var tasks []Task
 	for rows.Next() {
 		var task Task
-		if err = rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+		var raw sql.NullString
+		fields := taskFields(&task)
+		if withSnippet {
+			fields = append(fields, &raw)
+		}
+		if err = rows.Scan(fields...); err != nil {
 			return nil, fmt.Errorf("failed to parse tasks: %w", err)
 		}
+		task.Snippet = highlight(raw.String)
 		tasks = append(tasks, task)
 	}
 
@@ -108,16 +248,14 @@ func (s Storage) GetTasks(search, limit string) ([]Task, error) {
 	return tasks, nil
 }
 
-func (s Storage) GetTask(id int64) (*Task, error) {
-	query := `
-		SELECT id, date, title, comment, repeat
-		FROM scheduler
-		WHERE id = ?
+func (s SQLStore) GetTask(id int64) (*Task, error) {
+	query := selectTasks + `
+		WHERE scheduler.id = ?
 	`
-	row := s.db.QueryRow(query, id)
+	row := s.conn.QueryRow(query, id)
 
 	var task Task
-	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	err := scanTask(row, &task)
 	if err != nil {
 		return nil, fmt.Errorf("task not found")
 	}
@@ -125,10 +263,28 @@ func (s Storage) GetTask(id int64) (*Task, error) {
 	return &task, nil
 }
 
-func (s Storage) UpdateTask(task *Task) error {
+func (s SQLStore) UpdateTask(task *Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = updateTask(s.rebind(tx), task); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit task: %w", err)
+	}
+
+	return nil
+}
+
+func updateTask(conn querier, task *Task) error {
 	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`
 
-	res, err := s.db.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+	res, err := conn.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
@@ -142,13 +298,36 @@ func (s Storage) UpdateTask(task *Task) error {
 		return fmt.Errorf("task not found")
 	}
 
+	return saveDetails(conn, task)
+}
+
+// DeleteTask deletes the task in one transaction.
+func (s SQLStore) DeleteTask(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = deleteTask(s.rebind(tx), id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit task deletion: %w", err)
+	}
+
 	return nil
 }
 
-func (s Storage) DeleteTask(id int64) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
+// deleteTask deletes the task; its side tables follow by ON DELETE CASCADE,
+// while the word index is pruned by saveWords.
+func deleteTask(conn querier, id int64) error {
+	if err := saveWords(conn, id, ""); err != nil {
+		return err
+	}
 
-	res, err := s.db.Exec(query, id)
+	res, err := conn.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
@@ -165,43 +344,333 @@ func (s Storage) DeleteTask(id int64) error {
 	return nil
 }
 
-func (task *Task) Validate() error {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+func (s SQLStore) AdvanceTask(task *Task, missed []Task, finished bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	conn := s.rebind(tx)
 
-	if task.Title == "" {
-		return errors.New("task title is required")
+	for i := range missed {
+		if err = insertTask(conn, &missed[i]); err != nil {
+			return err
+		}
 	}
 
-	if task.Date == "" {
-		task.Date = today.Format(utils.DateFormat)
+	if finished {
+		err = deleteTask(conn, task.ID)
+	} else {
+		err = updateTask(conn, task)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit task: %w", err)
+	}
+
+	return nil
+}
+
+func scanTask(row scanner, task *Task) error {
+	return row.Scan(taskFields(task)...)
+}
+
+// taskFields lists where to scan the taskColumns of a task.
+func taskFields(task *Task) []any {
+	return []any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+		&task.Time, &task.TZ, &task.Until, &task.Count, &task.Done,
+		&task.CatchUp, &task.Anchor, &task.Ease, &task.Interval, &task.Reps}
+}
+
+func saveRecurrence(db execer, task *Task) error {
+	if !task.limited() {
+		if _, err := db.Exec(`DELETE FROM task_recurrence WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("failed to delete end conditions: %w", err)
+		}
+		return nil
+	}
+
+	query := `INSERT INTO task_recurrence (task_id, until, count, done) VALUES (?, ?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET until = excluded.until, count = excluded.count, done = excluded.done`
+	if _, err := db.Exec(query, task.ID, task.Until, task.Count, task.Done); err != nil {
+		return fmt.Errorf("failed to save end conditions: %w", err)
+	}
+	return nil
+}
+
+func saveTime(db execer, task *Task) error {
+	if task.Time == "" && task.TZ == "" {
+		if _, err := db.Exec(`DELETE FROM task_times WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("failed to delete task time: %w", err)
+		}
 		return nil
 	}
 
-	parsedDate, err := time.Parse(utils.DateFormat, task.Date)
+	query := `INSERT INTO task_times (task_id, time, tz) VALUES (?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET time = excluded.time, tz = excluded.tz`
+	if _, err := db.Exec(query, task.ID, task.Time, task.TZ); err != nil {
+		return fmt.Errorf("failed to save task time: %w", err)
+	}
+	return nil
+}
+
+// DefaultLocation is the time zone of tasks without their own one, taken from
+// TODO_TZ and falling back to the server's local zone.
+func DefaultLocation() *time.Location {
+	defaultLocationOnce.Do(func() {
+		defaultLocation = time.Local
+		if name := os.Getenv("TODO_TZ"); name != "" {
+			if loc, err := time.LoadLocation(name); err == nil {
+				defaultLocation = loc
+			}
+		}
+	})
+	return defaultLocation
+}
+
+func (task *Task) Location() (*time.Location, error) {
+	if task.TZ == "" {
+		return DefaultLocation(), nil
+	}
+
+	loc, err := time.LoadLocation(task.TZ)
 	if err != nil {
-		return errors.New("invalid date format, expected YYYYMMDD")
+		return nil, errors.New("unknown time zone: " + task.TZ)
 	}
+	return loc, nil
+}
 
-	if parsedDate.Before(today) {
-		if task.Repeat == "" {
-			task.Date = today.Format(utils.DateFormat)
-			return nil
+// Start returns the task's date and optional time in its time zone.
+func (task *Task) Start() (time.Time, error) {
+	loc, err := task.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	value, layout := task.Date, utils.DateFormat
+	if task.Time != "" {
+		value, layout = task.Date+" "+task.Time, utils.DateFormat+" "+utils.TimeFormat
+	}
+
+	start, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, errors.New("invalid date format, expected YYYYMMDD")
+	}
+	return start, nil
+}
+
+func (task *Task) SetStart(start time.Time) {
+	task.Date = start.Format(utils.DateFormat)
+	if task.Time != "" {
+		task.Time = start.Format(utils.TimeFormat)
+	}
+}
+
+// Rule parses the task's repeat rule and limits it by the task's end
+// conditions. The current date counts as one of the remaining occurrences,
+// both of the task's count and of a COUNT in the rule itself.
+func (task *Task) Rule(calendar utils.Calendar) (utils.Rule, error) {
+	rule, err := utils.ParseRule(task.Repeat)
+	if err != nil {
+		return nil, err
+	}
+	rule = utils.WithReview(rule, task.Review())
+
+	if start, err := task.Start(); err == nil {
+		if rule, err = utils.AnchorRule(rule, task.anchor(start)); err != nil {
+			return nil, err
 		}
+	}
+	rule = utils.WithCalendar(rule, calendar)
 
-		nextDate, err := utils.NextDate(today, task.Date, task.Repeat)
+	if task.Time != "" {
+		clock, err := time.Parse(utils.TimeFormat, task.Time)
 		if err != nil {
-			return err
+			return nil, errors.New("invalid time format, expected HH:MM")
+		}
+		rule = utils.WithClock(rule, clock.Hour(), clock.Minute())
+	}
+
+	var until time.Time
+	if task.Until != "" {
+		until, err = time.Parse(utils.DateFormat, task.Until)
+		if err != nil {
+			return nil, errors.New("invalid until format, expected YYYYMMDD")
+		}
+	}
+
+	count := int(task.Count)
+	if ruleCount := countOf(rule); ruleCount > 0 && (count == 0 || ruleCount < count) {
+		count = ruleCount
+	}
+	var remaining int
+	if count > 0 {
+		remaining = count - int(task.Done)
+		if remaining < 1 {
+			remaining = 1
+		}
+	}
+
+	return utils.WithLimits(rule, until, remaining), nil
+}
+
+// limited reports whether the task has end conditions, its own or a COUNT in
+// its repeat rule, and so keeps track of its done counter.
+func (task *Task) limited() bool {
+	if task.Until != "" || task.Count > 0 {
+		return true
+	}
+	rule, err := utils.ParseRule(task.Repeat)
+	return err == nil && countOf(rule) > 0
+}
+
+// countOf returns the COUNT rule stops after, or 0 when it has none.
+func countOf(rule utils.Rule) int {
+	if bounded, ok := rule.(utils.Bounded); ok {
+		_, count := bounded.Bounds()
+		return count
+	}
+	return 0
+}
+
+// anchor returns the date the task's repeat rule counts from, at the time of
+// day of start.
+func (task *Task) anchor(start time.Time) time.Time {
+	if task.Anchor == "" {
+		return start
+	}
+	date, err := time.ParseInLocation(utils.DateFormat, task.Anchor, start.Location())
+	if err != nil {
+		return start
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, start.Location())
+}
+
+func saveAnchor(db execer, task *Task) error {
+	if task.Anchor == "" || task.Anchor == task.Date {
+		if _, err := db.Exec(`DELETE FROM task_anchor WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("failed to delete task anchor: %w", err)
 		}
-		task.Date = nextDate
 		return nil
 	}
 
+	query := `INSERT INTO task_anchor (task_id, date) VALUES (?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET date = excluded.date`
+	if _, err := db.Exec(query, task.ID, task.Anchor); err != nil {
+		return fmt.Errorf("failed to save task anchor: %w", err)
+	}
+	return nil
+}
+
+// Missed returns the one-off tasks that Validate split off for occurrences
+// the task had already missed.
+func (task *Task) Missed() []Task {
+	return task.missed
+}
+
+// Validate checks the task and moves a repeating one off a past date, as well
+// as off a date among its exceptions.
+func (task *Task) Validate(calendar utils.Calendar, exceptions []time.Time) error {
+	if task.Title == "" {
+		return errors.New("task title is required")
+	}
+
+	if task.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+
+	if err := checkCatchUp(task.CatchUp); err != nil {
+		return err
+	}
+
+	if task.Time != "" {
+		if _, err := time.Parse(utils.TimeFormat, task.Time); err != nil {
+			return errors.New("invalid time format, expected HH:MM")
+		}
+	}
+
+	loc, err := task.Location()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	if task.Date == "" {
+		task.Date = today.Format(utils.DateFormat)
+	}
+
+	var rule utils.Rule
 	if task.Repeat != "" {
-		_, err = utils.NextDate(today, task.Date, task.Repeat)
+		// The rule is stored as written; anchoring it to the start date only
+		// serves the date computations below.
+		parsed, err := utils.ParseRule(task.Repeat)
 		if err != nil {
 			return err
 		}
+		rule, err = task.Rule(calendar)
+		if err != nil {
+			return err
+		}
+		rule = utils.WithExceptions(rule, exceptions)
+		task.Repeat = parsed.String()
+
+		if first, ok := utils.FirstTime(rule); ok && task.Time == "" {
+			task.Time = first
+		}
+		if _, ok := utils.ReviewOf(rule); !ok {
+			task.SetReview(utils.Review{})
+		}
+	} else if task.Until != "" || task.Count != 0 {
+		return errors.New("until and count require repeat")
+	} else {
+		task.SetReview(utils.Review{})
+	}
+
+	start, err := task.Start()
+	if err != nil {
+		return err
+	}
+
+	if rule == nil {
+		if start.Before(today) {
+			task.Date = today.Format(utils.DateFormat)
+		}
+		return nil
+	}
+
+	// A new task has missed nothing yet: it starts at its next occurrence
+	// whatever its catch-up policy.
+	policy := task.CatchUpPolicy()
+	if task.ID == 0 {
+		policy = CatchUpSkip
+	}
+	if task.missed, err = task.advance(rule, now, false, policy); err != nil {
+		return err
+	}
+
+	// The rule skips the exceptions after the start, but not the start itself.
+	for _, date := range exceptions {
+		if date.Format(utils.DateFormat) != task.Date {
+			continue
+		}
+		if start, err = task.Start(); err != nil {
+			return err
+		}
+		next, err := utils.NextAfter(rule, start, start)
+		if err != nil {
+			return err
+		}
+		task.SetStart(next)
+		break
+	}
+
+	if task.Until != "" && task.Date > task.Until {
+		return errors.New("task date is after until")
 	}
 
 	return nil
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"go_final_project/pkg/utils"
+)
+
+func TestValidateExceptions(t *testing.T) {
+	now := time.Now().In(DefaultLocation())
+	day := func(days int) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, now.Location())
+	}
+	exceptions := []time.Time{day(1), day(5)}
+
+	tests := []struct {
+		date time.Time
+		want time.Time
+	}{
+		{day(-1), day(3)},
+		{day(1), day(3)},
+		{day(5), day(7)},
+		{day(3), day(3)},
+	}
+	for _, tt := range tests {
+		task := Task{Date: tt.date.Format(utils.DateFormat), Title: "Бассейн", Repeat: "d 2"}
+		if err := task.Validate(nil, exceptions); err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want.Format(utils.DateFormat); task.Date != want {
+			t.Errorf("task from %s moved to %s, want %s", tt.date.Format(utils.DateFormat), task.Date, want)
+		}
+	}
+}
+
+func TestValidateFirstTime(t *testing.T) {
+	tests := []struct {
+		repeat string
+		tz     string
+		time   string
+		want   string
+	}{
+		{"c 0 9 * * *", "", "", "09:00"},
+		{"c 30 7,19 * * *", "America/New_York", "", "07:30"},
+		{"c 0 9 * * *", "Asia/Tokyo", "18:00", "18:00"},
+		{"h 2 08:00-20:00", "Europe/Moscow", "", "08:00"},
+		{"d 1", "Europe/Moscow", "", ""},
+	}
+
+	for _, tt := range tests {
+		task := Task{Title: "Созвон", Repeat: tt.repeat, TZ: tt.tz, Time: tt.time}
+		loc, err := task.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		task.Date = time.Now().In(loc).AddDate(0, 0, 2).Format(utils.DateFormat)
+		date := task.Date
+
+		if err := task.Validate(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if task.Time != tt.want {
+			t.Errorf("%q in %q: time = %q, want %q", tt.repeat, tt.tz, task.Time, tt.want)
+		}
+		if task.Date != date {
+			t.Errorf("%q in %q: date moved from %s to %s", tt.repeat, tt.tz, date, task.Date)
+		}
+	}
+}
+
+func TestValidateCatchUp(t *testing.T) {
+	now := time.Now().In(DefaultLocation())
+	day := func(days int) string {
+		return time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, now.Location()).Format(utils.DateFormat)
+	}
+
+	tests := []struct {
+		id     int64
+		policy string
+		date   string
+		missed int
+	}{
+		{0, CatchUpSkip, day(2), 0},
+		{0, CatchUpOne, day(2), 0},
+		{0, CatchUpEach, day(2), 0},
+		{1, CatchUpSkip, day(2), 0},
+		{1, CatchUpOne, day(-10), 0},
+		{1, CatchUpEach, day(2), 4},
+	}
+	for _, tt := range tests {
+		task := Task{ID: tt.id, Date: day(-10), Title: "Подкормка", Repeat: "d 3", CatchUp: tt.policy}
+		if err := task.Validate(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if task.Date != tt.date || len(task.Missed()) != tt.missed {
+			t.Errorf("task %d under %s: date %s with %d missed, want %s with %d",
+				tt.id, tt.policy, task.Date, len(task.Missed()), tt.date, tt.missed)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package fuzzy compares words by their trigrams, to find tasks despite typos.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Threshold is the least similarity at which two words count as a match.
+const Threshold = 0.3
+
+// IsWordRune reports whether r belongs to a word: letters, digits and marks,
+// as in the unicode61 tokenizer of FTS5.
+func IsWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+// Words returns the distinct words of text folded to lower case, in order.
+func Words(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !IsWordRune(r) }) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Trigrams returns the distinct trigrams of a word padded with two spaces in
+// front and one behind, so that the start of a word weighs more than its end.
+func Trigrams(word string) []string {
+	runes := []rune("  " + word + " ")
+	seen := make(map[string]bool)
+	var trigrams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		trigram := string(runes[i : i+3])
+		if !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return trigrams
+}
+
+// Similarity is the share of the trigrams of a and b they have in common:
+// 1 for equal words and 0 for words without a common trigram.
+func Similarity(a, b string) float64 {
+	ta, tb := Trigrams(a), Trigrams(b)
+	in := make(map[string]bool, len(ta))
+	for _, trigram := range ta {
+		in[trigram] = true
+	}
+	shared := 0
+	for _, trigram := range tb {
+		if in[trigram] {
+			shared++
+		}
+	}
+	return Jaccard(shared, len(ta), len(tb))
+}
+
+// Jaccard is the similarity of two sets of sizes a and b with shared common
+// elements.
+func Jaccard(shared, a, b int) float64 {
+	if a+b-shared == 0 {
+		return 0
+	}
+	return float64(shared) / float64(a+b-shared)
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var errUsage = errors.New("usage: migrate list | up [version] | down [version]")
+
+// Run carries out a migrate command from the command line:
+//
+//	list            shows every migration and whether it is applied
+//	up [version]    applies the pending migrations, up to version if given
+//	down [version]  rolls back the latest migration, or all above version
+func (d *Dialect) Run(db *sql.DB, args []string, out io.Writer) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errUsage
+	}
+
+	version := -1
+	if len(args) == 2 {
+		var err error
+		version, err = strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return errUsage
+		}
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return errUsage
+		}
+	case "up":
+		if version < 0 {
+			version = 0
+		}
+		if err := d.UpTo(db, version); err != nil {
+			return err
+		}
+	case "down":
+		if version < 0 {
+			current, err := d.Current(db)
+			if err != nil {
+				return err
+			}
+			version = d.previous(db, current)
+		}
+		if err := d.DownTo(db, version); err != nil {
+			return err
+		}
+	default:
+		return errUsage
+	}
+
+	return d.list(db, out)
+}
+
+// previous returns the version of the applied migration before current.
+func (d *Dialect) previous(db *sql.DB, current int) int {
+	states, err := d.Status(db)
+	if err != nil {
+		return current
+	}
+
+	version := 0
+	for _, s := range states {
+		if s.Applied() && s.Version < current {
+			version = s.Version
+		}
+	}
+	return version
+}
+
+func (d *Dialect) list(db *sql.DB, out io.Writer) error {
+	states, err := d.Status(db)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		applied := s.AppliedAt
+		if !s.Applied() {
+			applied = "pending"
+		}
+		fmt.Fprintf(out, "%04d  %-28s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
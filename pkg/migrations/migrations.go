@@ -0,0 +1,310 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const appliedFormat = "2006-01-02T15:04:05Z"
+
+//go:embed sql/*.sql sql/postgres/*.sql
+var sqlFiles embed.FS
+
+// Dialect is a database flavour with its own copy of the SQL migrations.
+// Both copies have the same versions and build the same schema.
+type Dialect struct {
+	Name   string
+	dir    string
+	bind   int
+	schema string
+	// lock serialises concurrent migrations until the end of the
+	// transaction, for databases shared by several servers.
+	lock string
+}
+
+var (
+	SQLite = &Dialect{
+		Name: "sqlite",
+		dir:  "sql",
+		bind: sqlx.QUESTION,
+		schema: `CREATE TABLE IF NOT EXISTS schema_migrations
+                (
+                    version    INTEGER PRIMARY KEY,
+                    name       CHAR(128) NOT NULL DEFAULT "",
+                    applied_at CHAR(20) NOT NULL DEFAULT ""
+                );`,
+	}
+
+	Postgres = &Dialect{
+		Name: "postgres",
+		dir:  "sql/postgres",
+		bind: sqlx.DOLLAR,
+		lock: `SELECT pg_advisory_xact_lock(7540)`,
+		schema: `CREATE TABLE IF NOT EXISTS schema_migrations
+                (
+                    version    INTEGER PRIMARY KEY,
+                    name       VARCHAR(128) NOT NULL DEFAULT '',
+                    applied_at VARCHAR(20) NOT NULL DEFAULT ''
+                );`,
+	}
+)
+
+// rebind rewrites a query written with ? placeholders for the dialect.
+func (d *Dialect) rebind(query string) string {
+	return sqlx.Rebind(d.bind, query)
+}
+
+// Migration changes the schema from Version-1 to Version. Up and Down run
+// inside the transaction that also records the change; a migration without
+// Down cannot be rolled back.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// State is a migration together with the time it was applied, which is
+// empty while it is pending.
+type State struct {
+	Migration
+	AppliedAt string
+}
+
+func (s State) Applied() bool {
+	return s.AppliedAt != ""
+}
+
+// goMigrations are the migrations written in Go, for changes that SQL alone
+// cannot express.
+func (d *Dialect) goMigrations() []Migration {
+	return []Migration{
+		{Version: 8, Name: "canonical_repeat", Up: d.canonicalRepeat, Down: restoreRepeat},
+		{Version: 11, Name: "index_words", Up: d.indexWords, Down: clearWords},
+	}
+}
+
+// All returns the embedded SQL migrations and the Go migrations ordered by
+// version. SQL migrations are read from NNNN_name.up.sql with an optional
+// NNNN_name.down.sql in the dialect's directory.
+func (d *Dialect) All() ([]Migration, error) {
+	byVersion := make(map[int]*Migration)
+	add := func(m Migration) error {
+		if _, ok := byVersion[m.Version]; ok {
+			return fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		byVersion[m.Version] = &m
+		return nil
+	}
+
+	ups, err := fs.Glob(sqlFiles, d.dir+"/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range ups {
+		base := strings.TrimSuffix(path.Base(file), ".up.sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name: %s", file)
+		}
+
+		m := Migration{Version: version, Name: name, Up: sqlStep(file)}
+		down := strings.TrimSuffix(file, ".up.sql") + ".down.sql"
+		if _, err := fs.Stat(sqlFiles, down); err == nil {
+			m.Down = sqlStep(down)
+		}
+		if err := add(m); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range d.goMigrations() {
+		if err := add(m); err != nil {
+			return nil, err
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+func sqlStep(file string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		query, err := sqlFiles.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(string(query))
+		return err
+	}
+}
+
+// Status lists every known migration and when it was applied.
+func (d *Dialect) Status(db *sql.DB) ([]State, error) {
+	all, err := d.All()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := d.appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, len(all))
+	for i, m := range all {
+		states[i] = State{Migration: m, AppliedAt: applied[m.Version]}
+	}
+	return states, nil
+}
+
+func (d *Dialect) appliedVersions(db *sql.DB) (map[int]string, error) {
+	if _, err := db.Exec(d.schema); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration.
+func (d *Dialect) Up(db *sql.DB) error {
+	return d.UpTo(db, 0)
+}
+
+// UpTo applies the pending migrations up to version, or all of them when
+// version is 0, in order and each in its own transaction.
+func (d *Dialect) UpTo(db *sql.DB, version int) error {
+	states, err := d.Status(db)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		if version > 0 && s.Version > version {
+			break
+		}
+		if s.Applied() {
+			continue
+		}
+		err = d.inTx(db, s, s.Up, func(tx *sql.Tx) error {
+			query := d.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`)
+			_, err := tx.Exec(query, s.Version, s.Name, time.Now().UTC().Format(appliedFormat))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownTo rolls back the applied migrations above version, newest first.
+func (d *Dialect) DownTo(db *sql.DB, version int) error {
+	states, err := d.Status(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(states) - 1; i >= 0; i-- {
+		s := states[i]
+		if s.Version <= version {
+			break
+		}
+		if !s.Applied() {
+			continue
+		}
+		if s.Down == nil {
+			return fmt.Errorf("migration %d %s cannot be rolled back", s.Version, s.Name)
+		}
+		err = d.inTx(db, s, s.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(d.rebind(`DELETE FROM schema_migrations WHERE version = ?`), s.Version)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Current returns the version of the latest applied migration.
+func (d *Dialect) Current(db *sql.DB) (int, error) {
+	states, err := d.Status(db)
+	if err != nil {
+		return 0, err
+	}
+
+	current := 0
+	for _, s := range states {
+		if s.Applied() {
+			current = s.Version
+		}
+	}
+	return current, nil
+}
+
+// inTx runs step and record in one transaction. Where the dialect can lock
+// the schema, it first checks that no other process has applied or rolled
+// back the migration since s was read, and does nothing if one has.
+func (d *Dialect) inTx(db *sql.DB, s State, step, record func(tx *sql.Tx) error) error {
+	m := s.Migration
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if d.lock != "" {
+		if _, err = tx.Exec(d.lock); err != nil {
+			return fmt.Errorf("failed to lock schema_migrations: %w", err)
+		}
+		var count int
+		query := d.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`)
+		if err = tx.QueryRow(query, m.Version).Scan(&count); err != nil {
+			return fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		if (count > 0) != s.Applied() {
+			return nil
+		}
+	}
+
+	if err = step(tx); err != nil {
+		return fmt.Errorf("migration %d %s failed: %w", m.Version, m.Name, err)
+	}
+	if err = record(tx); err != nil {
+		return fmt.Errorf("failed to record migration %d %s: %w", m.Version, m.Name, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d %s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "scheduler.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestUpAndDown(t *testing.T) {
+	db := openDB(t)
+	all, err := SQLite.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := all[len(all)-1].Version
+
+	if err = SQLite.UpTo(db, 3); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := SQLite.Current(db); current != 3 {
+		t.Fatalf("current = %d, want 3", current)
+	}
+	if tableExists(t, db, "task_times") {
+		t.Fatal("task_times exists before its migration")
+	}
+
+	if err = SQLite.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := SQLite.Current(db); current != latest {
+		t.Fatalf("current = %d, want %d", current, latest)
+	}
+	if err = SQLite.Up(db); err != nil {
+		t.Fatalf("repeated up: %v", err)
+	}
+
+	if err = SQLite.DownTo(db, 4); err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(t, db, "task_times") || !tableExists(t, db, "task_recurrence") {
+		t.Fatal("down to 4 did not drop exactly the later tables")
+	}
+
+	if err = SQLite.DownTo(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	if tableExists(t, db, "scheduler") {
+		t.Fatal("scheduler exists after rolling everything back")
+	}
+}
+
+func TestUpgradeLegacyDB(t *testing.T) {
+	db := openDB(t)
+	legacy := `CREATE TABLE scheduler
+                (
+                    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+                    date    CHAR(8) NOT NULL DEFAULT "",
+                    title   CHAR(255),
+                    comment TEXT,
+                    repeat  CHAR(128)
+                );
+              CREATE INDEX idx_scheduler_date ON scheduler (date);
+              INSERT INTO scheduler (date, title, comment, repeat) VALUES
+                ("20240126", "Бег", "", "w 7,1"),
+                ("20240126", "Старое правило", "", "q 1"),
+                ("20240126", "Оплата", "", "m -1,15,1  6,1");`
+	if _, err := db.Exec(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SQLite.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT repeat FROM scheduler ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var repeats []string
+	for rows.Next() {
+		var repeat string
+		if err = rows.Scan(&repeat); err != nil {
+			t.Fatal(err)
+		}
+		repeats = append(repeats, repeat)
+	}
+	if len(repeats) != 3 || repeats[0] != "w 1,7" || repeats[1] != "q 1" || repeats[2] != "m 1,15,-1 1,6" {
+		t.Fatalf("repeats = %q, want [w 1,7 q 1 m 1,15,-1 1,6]", repeats)
+	}
+	if !tableExists(t, db, "task_review") {
+		t.Fatal("task_review was not created")
+	}
+
+	var tasks, trigrams int
+	err = db.QueryRow(`SELECT COUNT(*) FROM task_words WHERE word = 'бег'`).Scan(&tasks)
+	if err == nil {
+		err = db.QueryRow(`SELECT COUNT(*) FROM word_trigrams WHERE word = 'бег'`).Scan(&trigrams)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks != 1 || trigrams != 4 {
+		t.Fatalf("бег is indexed for %d tasks with %d trigrams, want 1 and 4", tasks, trigrams)
+	}
+}
+
+func TestRestoreRepeat(t *testing.T) {
+	db := openDB(t)
+	if err := SQLite.UpTo(db, 7); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES
+		("20240126", "Бег", "", "w 7,1"),
+		("20240126", "Плавание", "", "w 3,2"),
+		("20240126", "Ходьба", "", "d 1")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SQLite.UpTo(db, 8); err != nil {
+		t.Fatal(err)
+	}
+	// An edit after the migration is kept on the way back.
+	if _, err = db.Exec(`UPDATE scheduler SET repeat = 'w 5' WHERE title = 'Плавание'`); err != nil {
+		t.Fatal(err)
+	}
+	if err = SQLite.DownTo(db, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	var repeats []string
+	rows, err := db.Query(`SELECT repeat FROM scheduler ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var repeat string
+		if err = rows.Scan(&repeat); err != nil {
+			t.Fatal(err)
+		}
+		repeats = append(repeats, repeat)
+	}
+	if len(repeats) != 3 || repeats[0] != "w 7,1" || repeats[1] != "w 5" || repeats[2] != "d 1" {
+		t.Fatalf("repeats = %q, want [w 7,1 w 5 d 1]", repeats)
+	}
+	if tableExists(t, db, "task_repeat_original") {
+		t.Fatal("task_repeat_original exists after rolling back")
+	}
+}
+
+func TestLegacyRepeat(t *testing.T) {
+	tests := []struct {
+		repeat string
+		want   string
+		ok     bool
+	}{
+		{"y", "y", true},
+		{"d 07", "d 7", true},
+		{" w  7,1,7 ", "w 1,7", true},
+		{"m -1,-2,31,1", "m 1,31,-2,-1", true},
+		{"m 15 12,1,1", "m 15 1,12", true},
+		{"d 401", "", false},
+		{"w 0", "", false},
+		{"m -3", "", false},
+		{"m 1 13", "", false},
+		{"y 2", "", false},
+		{"d+ 3", "", false},
+		{"FREQ=DAILY", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := legacyRepeat(tt.repeat)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("legacyRepeat(%q) = %q, %v, want %q, %v", tt.repeat, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	db := openDB(t)
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "x"}, {"list", "1"}} {
+		if err := SQLite.Run(db, args, nil); err != errUsage {
+			t.Errorf("Run(%q) = %v, want usage", args, err)
+		}
+	}
+}
+
+func TestDialectsMatch(t *testing.T) {
+	sqlite, err := SQLite.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := Postgres.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite has %d migrations, postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		s, p := sqlite[i], postgres[i]
+		if s.Version != p.Version || s.Name != p.Name || (s.Down == nil) != (p.Down == nil) {
+			t.Errorf("migration %d %s differs from postgres %d %s", s.Version, s.Name, p.Version, p.Name)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// canonicalRepeat rewrites the stored repeat rules of the original syntax in
+// the canonical form Task.Validate saved them in when this migration was
+// written. The originals are kept in task_repeat_original for the way back;
+// rules it does not know are left alone.
+func (d *Dialect) canonicalRepeat(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS task_repeat_original
+	(
+	    task_id   BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+	    original  VARCHAR(128) NOT NULL,
+	    canonical VARCHAR(128) NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, repeat FROM scheduler WHERE repeat <> ''`)
+	if err != nil {
+		return err
+	}
+
+	originals := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var repeat string
+		if err = rows.Scan(&id, &repeat); err != nil {
+			rows.Close()
+			return err
+		}
+		originals[id] = repeat
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	update := d.rebind(`UPDATE scheduler SET repeat = ? WHERE id = ?`)
+	keep := d.rebind(`INSERT INTO task_repeat_original (task_id, original, canonical) VALUES (?, ?, ?)`)
+	for id, original := range originals {
+		canonical, ok := legacyRepeat(original)
+		if !ok || canonical == original {
+			continue
+		}
+		if _, err = tx.Exec(keep, id, original, canonical); err != nil {
+			return fmt.Errorf("failed to keep the repeat of task %d: %w", id, err)
+		}
+		if _, err = tx.Exec(update, canonical, id); err != nil {
+			return fmt.Errorf("failed to update task %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// restoreRepeat puts back the original repeat rules of the tasks whose rule
+// is still the one canonicalRepeat wrote.
+func restoreRepeat(tx *sql.Tx) error {
+	_, err := tx.Exec(`UPDATE scheduler SET repeat = (
+		SELECT original FROM task_repeat_original WHERE task_id = scheduler.id AND canonical = scheduler.repeat
+	) WHERE id IN (
+		SELECT task_id FROM task_repeat_original WHERE canonical = scheduler.repeat
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DROP TABLE task_repeat_original`)
+	return err
+}
+
+// legacyRepeat normalizes a rule of the original syntax: "d N", "y",
+// "w weekdays" and "m days [months]", with lists sorted, without duplicates
+// and single spaces. It is frozen here, apart from the live parser, so that
+// what the migration writes does not change with later versions of it.
+func legacyRepeat(repeat string) (string, bool) {
+	fields := strings.Fields(repeat)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	switch {
+	case fields[0] == "y" && len(fields) == 1:
+		return "y", true
+	case fields[0] == "d" && len(fields) == 2:
+		days, err := strconv.Atoi(fields[1])
+		if err != nil || days < 1 || days > 400 {
+			return "", false
+		}
+		return "d " + strconv.Itoa(days), true
+	case fields[0] == "w" && len(fields) == 2:
+		weekdays, ok := legacyList(fields[1], 1, 7, func(a, b int) bool { return a < b })
+		if !ok {
+			return "", false
+		}
+		return "w " + weekdays, true
+	case fields[0] == "m" && (len(fields) == 2 || len(fields) == 3):
+		// Positive days come first, then -2 and -1, as in a month.
+		days, ok := legacyList(fields[1], -2, 31, func(a, b int) bool {
+			if (a > 0) != (b > 0) {
+				return a > 0
+			}
+			return a < b
+		})
+		if !ok {
+			return "", false
+		}
+		if len(fields) == 2 {
+			return "m " + days, true
+		}
+		months, ok := legacyList(fields[2], 1, 12, func(a, b int) bool { return a < b })
+		if !ok {
+			return "", false
+		}
+		return "m " + days + " " + months, true
+	}
+	return "", false
+}
+
+// legacyList sorts a comma-separated list of numbers from min to max, other
+// than 0, and drops its duplicates.
+func legacyList(list string, min, max int, less func(a, b int) bool) (string, bool) {
+	seen := make(map[int]bool)
+	var values []int
+	for _, part := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < min || value > max || value == 0 {
+			return "", false
+		}
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return less(values[i], values[j]) })
+
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ","), true
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduler_date;
+DROP TABLE IF EXISTS scheduler;
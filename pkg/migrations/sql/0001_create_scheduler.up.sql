@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS scheduler
+(
+    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    date    CHAR(8) NOT NULL DEFAULT "",
+    title   CHAR(255),
+    comment TEXT,
+    repeat  CHAR(128)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler (date);
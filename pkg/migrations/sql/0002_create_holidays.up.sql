@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS holidays
+(
+    date  CHAR(8) PRIMARY KEY,
+    title CHAR(255) NOT NULL DEFAULT ""
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS task_recurrence
+(
+    task_id INTEGER PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    until   CHAR(8) NOT NULL DEFAULT "",
+    count   INTEGER NOT NULL DEFAULT 0,
+    done    INTEGER NOT NULL DEFAULT 0
+);
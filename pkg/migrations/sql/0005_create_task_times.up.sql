@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS task_times
+(
+    task_id INTEGER PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    time    CHAR(5) NOT NULL DEFAULT "",
+    tz      CHAR(64) NOT NULL DEFAULT ""
+);
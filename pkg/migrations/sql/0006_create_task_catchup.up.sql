@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task_catchup
+(
+    task_id INTEGER PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    policy  CHAR(8) NOT NULL DEFAULT ""
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS task_review
+(
+    task_id  INTEGER PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    ease     REAL NOT NULL DEFAULT 0,
+    interval INTEGER NOT NULL DEFAULT 0,
+    reps     INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task_anchor
+(
+    task_id INTEGER PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    date    CHAR(8) NOT NULL DEFAULT ""
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS scheduler
+(
+    id      BIGSERIAL PRIMARY KEY,
+    date    VARCHAR(8) NOT NULL DEFAULT '',
+    title   VARCHAR(255),
+    comment TEXT,
+    repeat  VARCHAR(128)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler (date);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS holidays
+(
+    date  VARCHAR(8) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS task_exceptions
+(
+    task_id BIGINT NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+    date    VARCHAR(8) NOT NULL,
+    PRIMARY KEY (task_id, date)
+);
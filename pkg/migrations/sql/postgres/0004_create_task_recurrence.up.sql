@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS task_recurrence
+(
+    task_id BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    until   VARCHAR(8) NOT NULL DEFAULT '',
+    count   BIGINT NOT NULL DEFAULT 0,
+    done    BIGINT NOT NULL DEFAULT 0
+);
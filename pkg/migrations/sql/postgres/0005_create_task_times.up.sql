@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS task_times
+(
+    task_id BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    time    VARCHAR(5) NOT NULL DEFAULT '',
+    tz      VARCHAR(64) NOT NULL DEFAULT ''
+);
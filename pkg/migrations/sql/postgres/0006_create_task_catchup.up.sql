@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task_catchup
+(
+    task_id BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    policy  VARCHAR(8) NOT NULL DEFAULT ''
+);
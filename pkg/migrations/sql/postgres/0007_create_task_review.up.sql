@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS task_review
+(
+    task_id  BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    ease     DOUBLE PRECISION NOT NULL DEFAULT 0,
+    interval BIGINT NOT NULL DEFAULT 0,
+    reps     BIGINT NOT NULL DEFAULT 0
+);
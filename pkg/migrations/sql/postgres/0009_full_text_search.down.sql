@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduler_search;
+ALTER TABLE scheduler DROP COLUMN IF EXISTS search;
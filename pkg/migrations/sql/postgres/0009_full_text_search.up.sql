@@ -0,0 +1,6 @@
+ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(comment, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_scheduler_search ON scheduler USING GIN (search);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS word_trigrams;
+DROP INDEX IF EXISTS idx_task_words_task;
+DROP TABLE IF EXISTS task_words;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS task_words
+(
+    word    TEXT NOT NULL,
+    task_id BIGINT NOT NULL REFERENCES scheduler (id) ON DELETE CASCADE,
+    PRIMARY KEY (word, task_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_words_task ON task_words (task_id);
+CREATE TABLE IF NOT EXISTS word_trigrams
+(
+    trigram VARCHAR(3) NOT NULL,
+    word    TEXT NOT NULL,
+    PRIMARY KEY (trigram, word)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task_anchor
+(
+    task_id BIGINT PRIMARY KEY REFERENCES scheduler (id) ON DELETE CASCADE,
+    date    VARCHAR(8) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_final_project/pkg/fuzzy"
+)
+
+// indexWords fills the word index of the fuzzy search for the tasks saved
+// before it existed, as the stores do on every insert and update now.
+func (d *Dialect) indexWords(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, COALESCE(title, ''), COALESCE(comment, '') FROM scheduler`)
+	if err != nil {
+		return err
+	}
+
+	words := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var title, comment string
+		if err = rows.Scan(&id, &title, &comment); err != nil {
+			rows.Close()
+			return err
+		}
+		words[id] = fuzzy.Words(title + " " + comment)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	insertWord := d.rebind(`INSERT INTO task_words (word, task_id) VALUES (?, ?) ON CONFLICT DO NOTHING`)
+	insertTrigram := d.rebind(`INSERT INTO word_trigrams (trigram, word) VALUES (?, ?) ON CONFLICT DO NOTHING`)
+	for id, taskWords := range words {
+		for _, word := range taskWords {
+			if _, err = tx.Exec(insertWord, word, id); err != nil {
+				return fmt.Errorf("failed to index task %d: %w", id, err)
+			}
+			for _, trigram := range fuzzy.Trigrams(word) {
+				if _, err = tx.Exec(insertTrigram, trigram, word); err != nil {
+					return fmt.Errorf("failed to index task %d: %w", id, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func clearWords(tx *sql.Tx) error {
+	if _, err := tx.Exec(`DELETE FROM word_trigrams`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM task_words`)
+	return err
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	maxBusinessShift = 366
+	maxBusinessSkips = 10000
+)
+
+type Adjustment string
+
+const (
+	BusinessDaysOnly Adjustment = "bd"
+	NextBusinessDay  Adjustment = "next-bd"
+	PrevBusinessDay  Adjustment = "prev-bd"
+)
+
+// Calendar reports public holidays. Weekends are never business days, so a
+// calendar only needs to list the other days off.
+type Calendar interface {
+	IsHoliday(date time.Time) bool
+}
+
+// HolidaySet is a Calendar backed by a set of dates in DateFormat.
+type HolidaySet map[string]bool
+
+func (s HolidaySet) IsHoliday(date time.Time) bool {
+	return s[date.Format(DateFormat)]
+}
+
+// BusinessDay modifies a rule so that its occurrences fall on business days:
+// either dropping the other days or moving them to the nearest business day
+// after or before. Anchor is the unadjusted start of the wrapped rule, set by
+// AnchorRule: the rule is walked from there so that a shifted occurrence does
+// not shift the ones after it. Without an anchor, and for completion-relative
+// rules, each step starts from the previous adjusted occurrence.
+type BusinessDay struct {
+	Rule     Rule
+	Adjust   Adjustment
+	Calendar Calendar
+	Anchor   time.Time
+}
+
+func parseAdjustment(repeat string) (string, Adjustment, bool) {
+	i := strings.LastIndex(repeat, " ")
+	if i < 0 {
+		return repeat, "", false
+	}
+
+	switch adjust := Adjustment(repeat[i+1:]); adjust {
+	case BusinessDaysOnly, NextBusinessDay, PrevBusinessDay:
+		return repeat[:i], adjust, true
+	default:
+		return repeat, "", false
+	}
+}
+
+// WithCalendar attaches a holiday calendar to the business-day modifiers of
+// rule. Without a calendar only weekends are treated as days off.
+func WithCalendar(rule Rule, calendar Calendar) Rule {
+	if r, ok := rule.(Union); ok {
+		withCalendar, _ := r.mapRules(func(rule Rule) (Rule, error) {
+			return WithCalendar(rule, calendar), nil
+		})
+		return withCalendar
+	}
+	if r, ok := rule.(BusinessDay); ok {
+		r.Calendar = calendar
+		return r
+	}
+	return rule
+}
+
+func (r BusinessDay) IsBusinessDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return r.Calendar == nil || !r.Calendar.IsHoliday(date)
+}
+
+func (r BusinessDay) anchored() bool {
+	return !r.Anchor.IsZero() && !IsRelative(r.Rule)
+}
+
+func (r BusinessDay) Next(after time.Time) time.Time {
+	if r.anchored() {
+		return r.nextAnchored(after)
+	}
+
+	raw := after
+	for i := 0; i < maxBusinessSkips; i++ {
+		raw = r.Rule.Next(raw)
+		if raw.IsZero() {
+			return raw
+		}
+
+		date := r.adjust(raw)
+		if !date.IsZero() && date.After(after) {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// nextAnchored walks the raw occurrences from the anchor, jumping close to
+// after when the wrapped rule allows it, and adjusts only the one it returns.
+// An occurrence moves by at most maxBusinessShift days, so the raw ones
+// further back cannot land after after.
+func (r BusinessDay) nextAnchored(after time.Time) time.Time {
+	raw := r.Anchor
+	if from := after.AddDate(0, 0, -maxBusinessShift-1); from.After(raw) {
+		raw, _ = skipRule(r.Rule, raw, from)
+	}
+
+	for i := 0; i < maxOccurrenceSteps; i++ {
+		raw = r.Rule.Next(raw)
+		if raw.IsZero() {
+			return raw
+		}
+
+		date := r.adjust(raw)
+		if !date.IsZero() && date.After(after) {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func (r BusinessDay) adjust(date time.Time) time.Time {
+	step := 0
+	switch r.Adjust {
+	case NextBusinessDay:
+		step = 1
+	case PrevBusinessDay:
+		step = -1
+	}
+
+	for i := 0; i <= maxBusinessShift; i++ {
+		if r.IsBusinessDay(date) {
+			return date
+		}
+		if step == 0 {
+			break
+		}
+		date = date.AddDate(0, 0, step)
+	}
+	return time.Time{}
+}
+
+func (r BusinessDay) Bounds() (time.Time, int) {
+	if bounded, ok := r.Rule.(Bounded); ok {
+		return bounded.Bounds()
+	}
+	return time.Time{}, 0
+}
+
+func (r BusinessDay) String() string {
+	return r.Rule.String() + " " + string(r.Adjust)
+}
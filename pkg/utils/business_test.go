@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDayHolidayMidSeries(t *testing.T) {
+	calendar := HolidaySet{"20240108": true, "20240109": true}
+	tests := []struct {
+		repeat string
+		start  string
+		want   []string
+	}{
+		{"d 7 next-bd", "20240101", []string{"20240101", "20240110", "20240115", "20240122", "20240129"}},
+		{"d 7 prev-bd", "20240101", []string{"20240101", "20240105", "20240115", "20240122", "20240129"}},
+		{"d 7 bd", "20240101", []string{"20240101", "20240115", "20240122", "20240129", "20240205"}},
+		{"y next-bd", "20230109", []string{"20230109", "20240110", "20250109", "20260109", "20270111"}},
+		{"w 1 next-bd", "20240101", []string{"20240101", "20240110", "20240115", "20240122", "20240129"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			start, err := time.Parse(DateFormat, tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule, err = AnchorRule(rule, start); err != nil {
+				t.Fatal(err)
+			}
+			rule = WithCalendar(rule, calendar)
+
+			to := start.AddDate(5, 0, 0)
+			got := OccurrencesOf(rule, start, start, to, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d", len(got), len(tt.want))
+			}
+			for i, date := range got {
+				if date.Format(DateFormat) != tt.want[i] {
+					t.Errorf("occurrence %d = %s, want %s", i, date.Format(DateFormat), tt.want[i])
+				}
+			}
+
+			// Stepping from a shifted occurrence, as a task does once it has
+			// moved there, stays on the series.
+			for i := 1; i+1 < len(got); i++ {
+				if next := rule.Next(got[i]); !next.Equal(got[i+1]) {
+					t.Errorf("Next(%s) = %s, want %s", got[i].Format(DateFormat), next.Format(DateFormat), tt.want[i+1])
+				}
+			}
+
+			// Far in the future the anchored walk matches the stepwise one.
+			now := start.AddDate(3, 0, 0)
+			fast, err := NextAfter(rule, start, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slow, err := NextAfter(stepOnly{rule}, start, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fast.Equal(slow) {
+				t.Errorf("NextAfter = %s, stepwise %s", fast.Format(DateFormat), slow.Format(DateFormat))
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"time"
+)
+
+const (
+	TimeFormat     = "15:04"
+	DateTimeFormat = "20060102T1504"
+)
+
+// ParseDateTime parses a date in DateFormat or a date with a time of day in
+// DateTimeFormat and reports whether the time was given.
+func ParseDateTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if date, err := time.ParseInLocation(DateTimeFormat, value, loc); err == nil {
+		return date, true, nil
+	}
+	date, err := time.ParseInLocation(DateFormat, value, loc)
+	return date, false, err
+}
+
+// wallClock returns the time of day hour:minute on the given date. A time
+// skipped by a DST change moves forward by the length of the gap, so that it
+// never lands before the time asked for.
+func wallClock(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	date := time.Date(year, month, day, hour, minute, 0, 0, loc)
+	if date.Hour() == hour && date.Minute() == minute {
+		return date
+	}
+
+	_, offset := date.Add(-12 * time.Hour).Zone()
+	wall := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	return wall.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// Clocked puts every occurrence of a rule at the same wall-clock time, so the
+// time survives DST changes even when one occurrence had to be shifted.
+type Clocked struct {
+	Rule   Rule
+	Hour   int
+	Minute int
+}
+
+// WithClock fixes the time of day of the rule's occurrences. Cron rules carry
+// their own hours and minutes and switch to minute resolution instead, and
+// rules repeating within a day keep their own times.
+func WithClock(rule Rule, hour, minute int) Rule {
+	if r, ok := rule.(Cron); ok {
+		r.Timed = true
+		return r
+	}
+	if r, ok := rule.(Union); ok {
+		clocked, _ := r.mapRules(func(rule Rule) (Rule, error) {
+			return WithClock(rule, hour, minute), nil
+		})
+		return clocked
+	}
+	if IsSubDaily(rule) {
+		return rule
+	}
+	return Clocked{Rule: rule, Hour: hour, Minute: minute}
+}
+
+func (r Clocked) Next(after time.Time) time.Time {
+	next := r.Rule.Next(after)
+	if next.IsZero() {
+		return next
+	}
+	return wallClock(next.Year(), next.Month(), next.Day(), r.Hour, r.Minute, next.Location())
+}
+
+func (r Clocked) Bounds() (time.Time, int) {
+	if bounded, ok := r.Rule.(Bounded); ok {
+		return bounded.Bounds()
+	}
+	return time.Time{}, 0
+}
+
+func (r Clocked) String() string {
+	return r.Rule.String()
+}
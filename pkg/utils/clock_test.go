@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestNextDateTimeZones(t *testing.T) {
+	tests := []struct {
+		zone   string
+		now    string
+		date   string
+		repeat string
+		want   string
+	}{
+		// The same instant is a different day east of UTC.
+		{"UTC", "20240126T2000", "20240120", "d 1", "20240127"},
+		{"Asia/Tokyo", "20240127T0500", "20240120", "d 1", "20240128"},
+		{"Asia/Kolkata", "20240127T0130", "20240120T0900", "d 1", "20240127T0900"},
+
+		// Cron rules without a time start at their first time of day.
+		{"Europe/Moscow", "20240126T1000", "20240101", "c 0 9 * * 1", "20240129T0900"},
+		{"Europe/Moscow", "20240126T1200", "20240126", "c 30 8,18 * * *", "20240126T1830"},
+		{"Europe/Moscow", "20240126T1900", "20240126", "c 30 8,18 * * *", "20240127T0830"},
+		{"Europe/Moscow", "20240126T1000", "20240101T0700", "c 0 9 * * 1", "20240129T0900"},
+
+		// The wall-clock time survives DST changes.
+		{"America/New_York", "20240309T1200", "20240309T0930", "d 1", "20240310T0930"},
+		{"America/New_York", "20241102T1200", "20241102T0930", "d 1", "20241103T0930"},
+		{"America/New_York", "20240309T1200", "20240301", "c 0 9 * * *", "20240310T0900"},
+		{"America/New_York", "20241102T1200", "20241101", "c 0 9 * * *", "20241103T0900"},
+		{"Europe/Berlin", "20240330T1200", "20240323T0800", "w 7", "20240331T0800"},
+		// 02:30 does not exist on the spring-forward day and becomes 03:30.
+		{"America/New_York", "20240309T1200", "20240301", "c 30 2 * * *", "20240310T0330"},
+		{"America/New_York", "20240309T1200", "20240309T0230", "d 1", "20240310T0330"},
+		{"America/New_York", "20240310T0000", "20240310T0000", "h 1 00:00-12:00", "20240310T0100"},
+		{"America/New_York", "20240310T0100", "20240310T0000", "h 1 00:00-12:00", "20240310T0300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone+" "+tt.repeat, func(t *testing.T) {
+			loc := mustLocation(t, tt.zone)
+			now, err := time.ParseInLocation(DateTimeFormat, tt.now, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NextDate(now, tt.date, tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NextDate(%s, %s, %q) = %s, want %s", tt.now, tt.date, tt.repeat, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClockedAcrossDST(t *testing.T) {
+	loc := mustLocation(t, "America/New_York")
+	tests := []struct {
+		start time.Time
+		want  time.Duration
+	}{
+		{time.Date(2024, time.March, 9, 9, 30, 0, 0, loc), 23 * time.Hour},
+		{time.Date(2024, time.November, 2, 9, 30, 0, 0, loc), 25 * time.Hour},
+		{time.Date(2024, time.June, 1, 9, 30, 0, 0, loc), 24 * time.Hour},
+	}
+
+	rule := WithClock(Daily{Days: 1}, 9, 30)
+	for _, tt := range tests {
+		next := rule.Next(tt.start)
+		if got := next.Sub(tt.start); got != tt.want {
+			t.Errorf("from %s: next is %s later, want %s", tt.start, got, tt.want)
+		}
+		if hour, min, _ := next.Clock(); hour != 9 || min != 30 {
+			t.Errorf("from %s: next at %02d:%02d, want 09:30", tt.start, hour, min)
+		}
+	}
+}
+
+func TestFirstTime(t *testing.T) {
+	tests := []struct {
+		repeat string
+		want   string
+		ok     bool
+	}{
+		{"d 1", "", false},
+		{"h 2", "00:00", true},
+		{"min 30 09:15-18:00", "09:15", true},
+		{"c 0 9 * * 1", "09:00", true},
+		{"c 45 7,22 * * *", "07:45", true},
+		{"c */20 * * * *", "00:00", true},
+		{"c 0 9 * * 1 next-bd", "09:00", true},
+		{"c 0 9 * * 1; c 30 7 * * 5", "07:30", true},
+		{"w 1; c 15 6 * * 5", "06:15", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := FirstTime(rule)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("FirstTime = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
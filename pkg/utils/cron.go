@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cronFields      = 5
+	cronSearchYears = 400
+)
+
+type cronRange struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronRanges = [cronFields]cronRange{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{"day of week", 0, 7, []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// value parses a number of the field or one of its names, such as JAN or MON.
+func (r cronRange) value(s string) (int, error) {
+	for n, name := range r.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return n, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// CronSet is a bit set of the values allowed by one cron field.
+type CronSet uint64
+
+func (s CronSet) Has(n int) bool {
+	return s&(1<<uint(n)) != 0
+}
+
+// fitsMonths reports whether a set of days of the month has a day that
+// exists in one of the months.
+func (s CronSet) fitsMonths(months CronSet) bool {
+	for m := 1; m <= 12; m++ {
+		for d := 1; d <= maxMonthDays[m]; d++ {
+			if months.Has(m) && s.Has(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Cron is a standard 5-field cron schedule: minute, hour, day of month, month
+// and day of week, which also accept names such as JAN or MON. As in cron,
+// when both day fields are restricted a day matches if either of them does;
+// when either starts with * it has to match both.
+type Cron struct {
+	Minute  CronSet
+	Hour    CronSet
+	Day     CronSet
+	Month   CronSet
+	Weekday CronSet
+	Timed   bool
+
+	dayAny     bool
+	weekdayAny bool
+	expr       string
+}
+
+func parseCron(parts []string) (Rule, error) {
+	if err := checkParts(parts, cronFields+1, cronFields+1, "invalid repeat format for c: expected 5 fields"); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	var sets [cronFields]CronSet
+	for i, field := range parts[1:] {
+		set, err := parseCronField(field, cronRanges[i])
+		if err != nil {
+			return nil, shiftRuleError(err, offsets[i+1])
+		}
+		sets[i] = set
+	}
+
+	dayAny, weekdayAny := strings.HasPrefix(parts[3], "*"), strings.HasPrefix(parts[5], "*")
+	if weekdayAny && !dayAny && !sets[2].fitsMonths(sets[3]) {
+		return nil, newRuleError("c", CodeImpossible, parts[3], offsets[3],
+			"repeat never occurs: none of the days exists in the given months")
+	}
+
+	weekday := sets[4]
+	if weekday.Has(7) {
+		weekday = (weekday | 1) &^ (1 << 7)
+	}
+
+	return Cron{
+		Minute:     sets[0],
+		Hour:       sets[1],
+		Day:        sets[2],
+		Month:      sets[3],
+		Weekday:    weekday,
+		dayAny:     dayAny,
+		weekdayAny: weekdayAny,
+		expr:       strings.Join(parts[1:], " "),
+	}, nil
+}
+
+func parseCronField(field string, r cronRange) (CronSet, error) {
+	var set CronSet
+	items := strings.Split(field, ",")
+	for i, offset := range partOffsets(items, ",") {
+		item := items[i]
+		value, stepStr, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 || step > r.max {
+				return 0, newRuleError("c", CodeCronField, item, offset, "invalid "+r.name+" step: "+item)
+			}
+		}
+
+		low, high := r.min, r.max
+		switch {
+		case value == "*":
+		case strings.Contains(value, "-"):
+			from, to, _ := strings.Cut(value, "-")
+			var err1, err2 error
+			low, err1 = r.value(from)
+			high, err2 = r.value(to)
+			if err1 != nil || err2 != nil || low < r.min || high > r.max || low > high {
+				return 0, newRuleError("c", CodeCronField, item, offset, "invalid "+r.name+" range: "+item)
+			}
+		default:
+			n, err := r.value(value)
+			if err != nil || n < r.min || n > r.max {
+				return 0, newRuleError("c", CodeCronField, item, offset, "invalid "+r.name+": "+item)
+			}
+			low = n
+			if !hasStep {
+				high = n
+			}
+		}
+
+		for n := low; n <= high; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+// Next works with whole days unless the rule is Timed, in which case it also
+// honors the minute and hour fields.
+func (r Cron) Next(after time.Time) time.Time {
+	if r.Timed {
+		return r.nextTime(after)
+	}
+
+	limit := after.AddDate(cronSearchYears, 0, 0)
+	for day := after.AddDate(0, 0, 1); day.Before(limit); day = day.AddDate(0, 0, 1) {
+		if r.Month.Has(int(day.Month())) && r.matchesDay(day) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+func (r Cron) nextTime(after time.Time) time.Time {
+	year, month, day := after.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, after.Location())
+	limit := date.AddDate(cronSearchYears, 0, 0)
+
+	for ; date.Before(limit); date = date.AddDate(0, 0, 1) {
+		if !r.Month.Has(int(date.Month())) || !r.matchesDay(date) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !r.Hour.Has(hour) {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !r.Minute.Has(minute) {
+					continue
+				}
+				next := wallClock(date.Year(), date.Month(), date.Day(), hour, minute, date.Location())
+				if next.After(after) {
+					return next
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// firstMinute returns the earliest time of day of the rule in minutes.
+func (r Cron) firstMinute() (int, bool) {
+	for hour := 0; hour < 24; hour++ {
+		if !r.Hour.Has(hour) {
+			continue
+		}
+		for minute := 0; minute < 60; minute++ {
+			if r.Minute.Has(minute) {
+				return hour*60 + minute, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (r Cron) matchesDay(date time.Time) bool {
+	dayMatch := r.Day.Has(date.Day())
+	weekdayMatch := r.Weekday.Has(int(date.Weekday()))
+
+	if r.dayAny || r.weekdayAny {
+		return dayMatch && weekdayMatch
+	}
+	return dayMatch || weekdayMatch
+}
+
+func (r Cron) String() string {
+	return "c " + r.expr
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field string
+		r     cronRange
+		want  []int
+	}{
+		{"5", cronRanges[0], []int{5}},
+		{"1,15,30", cronRanges[0], []int{1, 15, 30}},
+		{"*/15", cronRanges[0], []int{0, 15, 30, 45}},
+		{"9-12", cronRanges[1], []int{9, 10, 11, 12}},
+		{"8-18/4", cronRanges[1], []int{8, 12, 16}},
+		{"20/2", cronRanges[1], []int{20, 22}},
+		{"1-3,28-31", cronRanges[2], []int{1, 2, 3, 28, 29, 30, 31}},
+		{"jan,JUL", cronRanges[3], []int{1, 7}},
+		{"Oct-Dec", cronRanges[3], []int{10, 11, 12}},
+		{"mon-fri", cronRanges[4], []int{1, 2, 3, 4, 5}},
+		{"SUN,sat", cronRanges[4], []int{0, 6}},
+		{"*/3", cronRanges[3], []int{1, 4, 7, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			set, err := parseCronField(tt.field, tt.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for n := tt.r.min; n <= tt.r.max; n++ {
+				if set.Has(n) {
+					got = append(got, n)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		repeat string
+		code   string
+		token  string
+	}{
+		{"c 0 9 * *", CodeFormat, ""},
+		{"c 60 9 * * *", CodeCronField, "60"},
+		{"c 0 24 * * *", CodeCronField, "24"},
+		{"c 0 9 0 * *", CodeCronField, "0"},
+		{"c 0 9 * 13 *", CodeCronField, "13"},
+		{"c 0 9 * * 8", CodeCronField, "8"},
+		{"c 0 9 5-1 * *", CodeCronField, "5-1"},
+		{"c */0 9 * * *", CodeCronField, "*/0"},
+		{"c 0 9 * foo *", CodeCronField, "foo"},
+		{"c 0 9 * * mon-", CodeCronField, "mon-"},
+		{"c 0 9 * * fri-mon", CodeCronField, "fri-mon"},
+		{"c 0 9 30,31 feb *", CodeImpossible, "30,31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			_, err := ParseRule(tt.repeat)
+			ruleErr, ok := err.(*RuleError)
+			if !ok {
+				t.Fatalf("err = %v, want a *RuleError", err)
+			}
+			if ruleErr.Code != tt.code || (tt.token != "" && ruleErr.Token != tt.token) {
+				t.Errorf("got %s %q, want %s %q", ruleErr.Code, ruleErr.Token, tt.code, tt.token)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		repeat string
+		after  string
+		want   []string
+	}{
+		{"c 0 9 * * 1-5", "20240105", []string{"20240108", "20240109", "20240110"}},
+		{"c 0 9 * * mon-fri", "20240105", []string{"20240108", "20240109", "20240110"}},
+		{"c 0 9 * * 0", "20240101", []string{"20240107", "20240114"}},
+		{"c 0 9 * * 7", "20240101", []string{"20240107", "20240114"}},
+		{"c 0 9 1,15 * *", "20240101", []string{"20240115", "20240201", "20240215"}},
+		{"c 0 9 1-10/3 * *", "20240101", []string{"20240104", "20240107", "20240110", "20240201"}},
+		{"c 0 9 -1 * *", "", nil},
+		{"c 0 9 29 feb *", "20240301", []string{"20280229"}},
+		{"c 0 9 1 jan,jul *", "20240101", []string{"20240701", "20250101"}},
+		// Both day fields restricted: either of them.
+		{"c 0 9 13 * fri", "20240101", []string{"20240105", "20240112", "20240113", "20240119", "20240126"}},
+		// A day field starting with * restricts together with the other.
+		{"c 0 9 */2 * mon", "20231231", []string{"20240101", "20240115", "20240129", "20240205"}},
+		{"c 0 9 13 * */1", "20240101", []string{"20240113", "20240213"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			rule, err := ParseRule(tt.repeat)
+			if tt.want == nil {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			date := mustDate(t, tt.after)
+			for _, want := range tt.want {
+				date = rule.Next(date)
+				if got := date.Format(DateFormat); got != want {
+					t.Fatalf("got %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCronNextTimed(t *testing.T) {
+	tests := []struct {
+		repeat string
+		after  string
+		want   []string
+	}{
+		{"c 30 8,18 * * *", "20240101T0900", []string{"20240101T1830", "20240102T0830"}},
+		{"c */20 9 * * *", "20240101T0920", []string{"20240101T0940", "20240102T0900"}},
+		{"c 0 9-11 * * sat", "20240106T1000", []string{"20240106T1100", "20240113T0900"}},
+		{"c 0 0 1 * *", "20240131T2359", []string{"20240201T0000", "20240301T0000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule = WithClock(rule, 0, 0)
+
+			date, err := time.Parse(DateTimeFormat, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				date = rule.Next(date)
+				if got := date.Format(DateTimeFormat); got != want {
+					t.Fatalf("got %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
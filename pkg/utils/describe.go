@@ -0,0 +1,895 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LangRussian = "ru"
+	LangEnglish = "en"
+)
+
+// maxCronTimes is the most times of day a cron description lists one by one.
+const maxCronTimes = 6
+
+type describer interface {
+	daily(days int) string
+	weekly(weekdays []int, interval int) string
+	monthly(rule Monthly) string
+	yearly(rule Yearly) string
+	relative(rule Relative) string
+	intraday(rule Intraday) string
+	review() string
+	rrule(rule RRule) string
+	cron(rule Cron) string
+	business(adjust Adjustment) string
+	limits(until time.Time, count int) string
+	union(texts []string) string
+}
+
+func DescribeRule(repeat, lang string) (string, error) {
+	rule, err := ParseRule(repeat)
+	if err != nil {
+		return "", err
+	}
+	return Describe(rule, lang)
+}
+
+func Describe(rule Rule, lang string) (string, error) {
+	var d describer
+	switch lang {
+	case LangRussian, "":
+		d = russian{}
+	case LangEnglish:
+		d = english{}
+	default:
+		return "", errors.New("unsupported language: " + lang)
+	}
+	return describe(d, rule)
+}
+
+func describe(d describer, rule Rule) (string, error) {
+	switch r := rule.(type) {
+	case Daily:
+		return d.daily(r.Days), nil
+	case Weekly:
+		return d.weekly(r.Weekdays, r.Interval), nil
+	case Monthly:
+		return d.monthly(r), nil
+	case Yearly:
+		return d.yearly(r), nil
+	case Relative:
+		return d.relative(r), nil
+	case Intraday:
+		return d.intraday(r), nil
+	case Review:
+		return d.review(), nil
+	case RRule:
+		return d.rrule(r), nil
+	case Cron:
+		return d.cron(r), nil
+	case Union:
+		texts := make([]string, len(r.Rules))
+		for i, rule := range r.Rules {
+			text, err := describe(d, rule)
+			if err != nil {
+				return "", err
+			}
+			texts[i] = text
+		}
+		return d.union(texts), nil
+	case BusinessDay:
+		text, err := describe(d, r.Rule)
+		if err != nil {
+			return "", err
+		}
+		return text + d.business(r.Adjust), nil
+	case Clocked:
+		return describe(d, r.Rule)
+	case Limited:
+		text, err := describe(d, r.Rule)
+		if err != nil {
+			return "", err
+		}
+		return text + d.limits(r.Until, r.Count), nil
+	case Excluding:
+		return describe(d, r.Rule)
+	default:
+		return "", fmt.Errorf("repeat %q has no description", rule)
+	}
+}
+
+func joinWords(items []string, and string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + " " + and + " " + items[len(items)-1]
+	}
+}
+
+// cronField is a cron field reduced to what a description needs: every value
+// (*), a step from the lowest value (*/15) or a plain list.
+type cronField struct {
+	values []int
+	all    bool
+	step   int
+}
+
+func newCronField(set CronSet, min, max int) cronField {
+	var f cronField
+	for n := min; n <= max; n++ {
+		if set.Has(n) {
+			f.values = append(f.values, n)
+		}
+	}
+	f.all = len(f.values) == max-min+1
+
+	if len(f.values) > 1 && f.values[0] == min && !f.all {
+		step := f.values[1] - f.values[0]
+		for i, n := range f.values {
+			if n != min+i*step {
+				step = 0
+				break
+			}
+		}
+		if step > 0 && f.values[len(f.values)-1]+step > max {
+			f.step = step
+		}
+	}
+	return f
+}
+
+func (f cronField) list() bool {
+	return !f.all && f.step == 0
+}
+
+// items renders the values, folding runs of three or more into ranges such
+// as 9–17.
+func (f cronField) items() []string {
+	var items []string
+	for i := 0; i < len(f.values); {
+		j := i
+		for j+1 < len(f.values) && f.values[j+1] == f.values[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			items = append(items, strconv.Itoa(f.values[i])+"–"+strconv.Itoa(f.values[j]))
+			i = j + 1
+			continue
+		}
+		items = append(items, strconv.Itoa(f.values[i]))
+		i++
+	}
+	return items
+}
+
+// cronTimes returns the times of day of a cron rule when it has a few fixed
+// ones, sorted, or nil.
+func cronTimes(minute, hour cronField) []string {
+	if !minute.list() || !hour.list() || len(minute.values)*len(hour.values) > maxCronTimes {
+		return nil
+	}
+	var times []string
+	for _, h := range hour.values {
+		for _, m := range minute.values {
+			times = append(times, clockString(h*60+m))
+		}
+	}
+	return times
+}
+
+// cronWeekdays returns the ISO weekdays of a cron rule, with Sunday last.
+func cronWeekdays(rule Cron) []int {
+	var days []int
+	for day := 1; day <= 7; day++ {
+		if rule.Weekday.Has(day % 7) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func isWorkweek(days []int) bool {
+	return len(days) == 5 && days[0] == 1 && days[4] == 5
+}
+
+func isWeekend(days []int) bool {
+	return len(days) == 2 && days[0] == 6 && days[1] == 7
+}
+
+func weekdayNumsISO(days []WeekdayNum) []int {
+	result := make([]int, len(days))
+	for i, wd := range days {
+		result[i] = isoWeekdayOf(wd.Day)
+	}
+	return result
+}
+
+func hasOrdinals(days []WeekdayNum) bool {
+	for _, wd := range days {
+		if wd.N != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type russian struct{}
+
+var (
+	ruWeekdaysDative = [8]string{"", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+	ruWeekdaysAccus  = [8]string{"", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу", "воскресенье"}
+	ruWeekdayGender  = [8]int{0, 0, 0, 1, 0, 1, 1, 2}
+	ruMonthsGenitive = [13]string{"", "января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrepos   = [13]string{"", "январе", "феврале", "марте", "апреле", "мае", "июне", "июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+	ruOrdinals       = [3][6]string{
+		{"", "первый", "второй", "третий", "четвёртый", "пятый"},
+		{"", "первую", "вторую", "третью", "четвёртую", "пятую"},
+		{"", "первое", "второе", "третье", "четвёртое", "пятое"},
+	}
+	ruLast        = [3]string{"последний", "последнюю", "последнее"}
+	ruPenultimate = [3]string{"предпоследний", "предпоследнюю", "предпоследнее"}
+)
+
+// ruPlural picks the form of a noun for n: one (1 день), few (2 дня) or many
+// (5 дней).
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return few
+	default:
+		return many
+	}
+}
+
+// ruEvery renders "every n units", where every is the singular "каждый" or
+// "каждую" matching the gender of the unit.
+func ruEvery(n int, every, one, few, many string) string {
+	return ruPlural(n, every, "каждые", "каждые") + " " + strconv.Itoa(n) + " " + ruPlural(n, one, few, many)
+}
+
+// ruIn adds the preposition "в", which becomes "во" before words such as
+// "вторник".
+func ruIn(word string) string {
+	if strings.HasPrefix(word, "вт") {
+		return "во " + word
+	}
+	return "в " + word
+}
+
+func (russian) daily(days int) string {
+	if days == 1 {
+		return "каждый день"
+	}
+	return ruEvery(days, "каждый", "день", "дня", "дней")
+}
+
+func (russian) weekdays(weekdays []int) string {
+	names := make([]string, len(weekdays))
+	for i, day := range weekdays {
+		names[i] = ruWeekdaysDative[day]
+	}
+	return "по " + joinWords(names, "и")
+}
+
+func (r russian) weekly(weekdays []int, interval int) string {
+	if interval > 1 {
+		return ruEvery(interval, "каждую", "неделю", "недели", "недель") + " " + r.weekdays(weekdays)
+	}
+	return r.weekdays(weekdays)
+}
+
+func (russian) monthDay(day int) string {
+	switch day {
+	case -1:
+		return "в последний день"
+	case -2:
+		return "в предпоследний день"
+	}
+	if day < 0 {
+		return "в " + strconv.Itoa(-day) + "-й с конца день"
+	}
+	return strconv.Itoa(day) + "-го"
+}
+
+func (russian) nthWeekday(wd WeekdayNum) string {
+	day := isoWeekdayOf(wd.Day)
+	gender := ruWeekdayGender[day]
+
+	var ordinal string
+	switch {
+	case wd.N == -1:
+		ordinal = ruLast[gender]
+	case wd.N == -2:
+		ordinal = ruPenultimate[gender]
+	case wd.N > 0 && wd.N <= maxMonthlyOrdinal:
+		ordinal = ruOrdinals[gender][wd.N]
+	case wd.N < 0 && wd.N >= -maxMonthlyOrdinal:
+		ordinal = ruOrdinals[gender][-wd.N] + " с конца"
+	default:
+		ordinal = strconv.Itoa(wd.N) + "-" + [3]string{"й", "ю", "е"}[gender]
+	}
+	return ruIn(ordinal + " " + ruWeekdaysAccus[day])
+}
+
+func (russian) months(months []int, names [13]string) string {
+	items := make([]string, len(months))
+	for i, month := range months {
+		items[i] = names[month]
+	}
+	return joinWords(items, "и")
+}
+
+func (r russian) monthly(rule Monthly) string {
+	var items []string
+	for _, day := range rule.Days {
+		items = append(items, r.monthDay(day))
+	}
+	for _, wd := range rule.Weekdays {
+		items = append(items, r.nthWeekday(wd))
+	}
+
+	months := "каждого месяца"
+	if len(rule.Months) > 0 {
+		months = r.months(rule.Months, ruMonthsGenitive)
+	}
+	return joinWords(items, "и") + " " + months
+}
+
+func (russian) yearly(rule Yearly) string {
+	text := ruEvery(rule.interval(), "каждый", "год", "года", "лет")
+	if rule.interval() == 1 {
+		text = "каждый год"
+	}
+
+	if len(rule.Dates) > 0 {
+		dates := make([]string, len(rule.Dates))
+		for i, date := range rule.Dates {
+			dates[i] = strconv.Itoa(date.Day) + " " + ruMonthsGenitive[date.Month]
+		}
+		text += " " + joinWords(dates, "и")
+	}
+
+	switch {
+	case rule.Leap == LeapFeb28:
+		text += ", в невисокосные годы 28 февраля"
+	case rule.Leap == LeapMar1:
+		text += ", в невисокосные годы 1 марта"
+	case len(rule.Dates) == 1 && rule.hasLeapDay():
+		text += ", только в високосные годы"
+	case rule.Leap == LeapOnly || rule.hasLeapDay():
+		text += ", 29 февраля только в високосные годы"
+	}
+	return text
+}
+
+func (russian) relative(rule Relative) string {
+	var period string
+	switch rule.Unit {
+	case "w":
+		period = ruPlural(rule.N, "неделю", "недели", "недель")
+	case "m":
+		period = ruPlural(rule.N, "месяц", "месяца", "месяцев")
+	case "y":
+		period = ruPlural(rule.N, "год", "года", "лет")
+	default:
+		period = ruPlural(rule.N, "день", "дня", "дней")
+	}
+	if rule.N == 1 {
+		return "через " + period + " после выполнения"
+	}
+	return "через " + strconv.Itoa(rule.N) + " " + period + " после выполнения"
+}
+
+func (russian) intraday(rule Intraday) string {
+	var text string
+	switch {
+	case rule.Unit == "h" && rule.N == 1:
+		text = "каждый час"
+	case rule.Unit == "h":
+		text = ruEvery(rule.N, "каждый", "час", "часа", "часов")
+	case rule.N == 1:
+		text = "каждую минуту"
+	default:
+		text = ruEvery(rule.N, "каждую", "минуту", "минуты", "минут")
+	}
+	if rule.Window {
+		text += " с " + clockString(rule.From) + " до " + clockString(rule.To)
+	}
+	return text
+}
+
+func (russian) review() string {
+	return "интервальное повторение по оценке выполнения"
+}
+
+func (r russian) rrule(rule RRule) string {
+	var text string
+	switch rule.Freq {
+	case FreqDaily:
+		text = "ежедневно"
+		if rule.Interval > 1 {
+			text = ruEvery(rule.Interval, "каждый", "день", "дня", "дней")
+		}
+	case FreqWeekly:
+		text = "еженедельно"
+		if rule.Interval > 1 {
+			text = ruEvery(rule.Interval, "каждую", "неделю", "недели", "недель")
+		}
+	case FreqMonthly:
+		text = "ежемесячно"
+		if rule.Interval > 1 {
+			text = ruEvery(rule.Interval, "каждый", "месяц", "месяца", "месяцев")
+		}
+	case FreqYearly:
+		text = "ежегодно"
+		if rule.Interval > 1 {
+			text = ruEvery(rule.Interval, "каждый", "год", "года", "лет")
+		}
+	}
+
+	if len(rule.ByDay) > 0 {
+		if hasOrdinals(rule.ByDay) {
+			items := make([]string, len(rule.ByDay))
+			for i, wd := range rule.ByDay {
+				items[i] = r.nthWeekday(wd)
+			}
+			text += " " + joinWords(items, "и")
+		} else {
+			text += " " + r.weekdays(weekdayNumsISO(rule.ByDay))
+		}
+	}
+	if len(rule.ByMonthDay) > 0 {
+		items := make([]string, len(rule.ByMonthDay))
+		for i, day := range rule.ByMonthDay {
+			items[i] = r.monthDay(day)
+		}
+		text += " " + joinWords(items, "и")
+		if rule.ByMonthDay[len(rule.ByMonthDay)-1] > 0 {
+			text += " числа"
+		}
+	}
+	if len(rule.ByMonth) > 0 {
+		text += " в " + r.months(rule.ByMonth, ruMonthsPrepos)
+	}
+	if len(rule.BySetPos) > 0 {
+		items := make([]string, len(rule.BySetPos))
+		for i, pos := range rule.BySetPos {
+			items[i] = r.setPos(pos)
+		}
+		text += ", только " + joinWords(items, "и") + " по счёту"
+	}
+	return text + r.limits(rule.Until, rule.Count)
+}
+
+func (russian) setPos(pos int) string {
+	switch {
+	case pos == -1:
+		return "последний"
+	case pos == -2:
+		return "предпоследний"
+	case pos < 0:
+		return strconv.Itoa(-pos) + "-й с конца"
+	}
+	return strconv.Itoa(pos) + "-й"
+}
+
+func (r russian) cron(rule Cron) string {
+	minute, hour := newCronField(rule.Minute, 0, 59), newCronField(rule.Hour, 0, 23)
+	when := r.cronDays(rule)
+
+	if times := cronTimes(minute, hour); times != nil {
+		if when == "" {
+			when = "ежедневно"
+		}
+		return when + " в " + joinWords(times, "и")
+	}
+
+	var text string
+	switch {
+	case minute.all:
+		text = "каждую минуту"
+	case minute.step > 0:
+		text = ruEvery(minute.step, "каждую", "минуту", "минуты", "минут")
+	default:
+		last := minute.values[len(minute.values)-1]
+		text = "в " + joinWords(minute.items(), "и") + " " + ruPlural(last, "минуту", "минуты", "минут")
+		if hour.all {
+			text += " каждого часа"
+		}
+	}
+	switch {
+	case hour.step > 0:
+		text += " " + ruEvery(hour.step, "каждый", "час", "часа", "часов")
+	case hour.list():
+		text += " в часы " + joinWords(hour.items(), "и")
+	}
+
+	if when != "" {
+		text += " " + when
+	}
+	return text
+}
+
+// cronDays describes the days of a cron rule, or returns an empty string when
+// it runs every day. Both day fields must match when either starts with *.
+func (r russian) cronDays(rule Cron) string {
+	var items []string
+	if day := newCronField(rule.Day, 1, 31); !day.all {
+		switch {
+		case len(day.values) == 1:
+			items = append(items, r.monthDay(day.values[0])+" числа")
+		case day.step > 0:
+			items = append(items, "каждый "+strconv.Itoa(day.step)+"-й день месяца")
+		default:
+			items = append(items, "по числам "+joinWords(day.items(), "и"))
+		}
+	}
+	if days := cronWeekdays(rule); len(days) < 7 {
+		switch {
+		case isWorkweek(days):
+			items = append(items, "по будням")
+		case isWeekend(days):
+			items = append(items, "по выходным")
+		default:
+			items = append(items, r.weekdays(days))
+		}
+	}
+
+	join := " или "
+	if rule.dayAny || rule.weekdayAny {
+		join = " и "
+	}
+	text := strings.Join(items, join)
+	if month := newCronField(rule.Month, 1, 12); !month.all {
+		if text == "" {
+			text = "каждый день"
+		}
+		text += " в " + r.months(month.values, ruMonthsPrepos)
+	}
+	return text
+}
+
+func (russian) business(adjust Adjustment) string {
+	switch adjust {
+	case NextBusinessDay:
+		return ", с переносом на следующий рабочий день"
+	case PrevBusinessDay:
+		return ", с переносом на предыдущий рабочий день"
+	default:
+		return ", только в рабочие дни"
+	}
+}
+
+func (russian) limits(until time.Time, count int) string {
+	var text string
+	if count > 0 {
+		text += ", " + strconv.Itoa(count) + " " + ruPlural(count, "раз", "раза", "раз")
+	}
+	if !until.IsZero() {
+		text += ", до " + until.Format("02.01.2006")
+	}
+	return text
+}
+
+// union keeps the descriptions apart with semicolons, as they may contain
+// commas of their own.
+func (russian) union(texts []string) string {
+	return strings.Join(texts, "; а также ")
+}
+
+type english struct{}
+
+var (
+	enOrdinalWords = [6]string{"", "first", "second", "third", "fourth", "fifth"}
+)
+
+func enOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func (english) daily(days int) string {
+	if days == 1 {
+		return "every day"
+	}
+	return "every " + strconv.Itoa(days) + " days"
+}
+
+func (english) weekdays(weekdays []int) string {
+	names := make([]string, len(weekdays))
+	for i, day := range weekdays {
+		names[i] = time.Weekday(day % 7).String()
+	}
+	return joinWords(names, "and")
+}
+
+func (e english) weekly(weekdays []int, interval int) string {
+	if interval > 1 {
+		return "every " + strconv.Itoa(interval) + " weeks on " + e.weekdays(weekdays)
+	}
+	return "every " + e.weekdays(weekdays)
+}
+
+func (english) monthDay(day int) string {
+	switch day {
+	case -1:
+		return "the last day"
+	case -2:
+		return "the second to last day"
+	}
+	if day < 0 {
+		return "the " + enOrdinal(-day) + " to last day"
+	}
+	return "the " + enOrdinal(day)
+}
+
+func (english) nthWeekday(wd WeekdayNum) string {
+	var ordinal string
+	switch {
+	case wd.N == -1:
+		ordinal = "last"
+	case wd.N == -2:
+		ordinal = "second to last"
+	case wd.N > 0 && wd.N <= maxMonthlyOrdinal:
+		ordinal = enOrdinalWords[wd.N]
+	case wd.N > 0:
+		ordinal = enOrdinal(wd.N)
+	default:
+		ordinal = enOrdinal(-wd.N) + " to last"
+	}
+	return "the " + ordinal + " " + wd.Day.String()
+}
+
+func (english) months(months []int) string {
+	names := make([]string, len(months))
+	for i, month := range months {
+		names[i] = time.Month(month).String()
+	}
+	return joinWords(names, "and")
+}
+
+func (e english) monthly(rule Monthly) string {
+	var items []string
+	for _, day := range rule.Days {
+		items = append(items, e.monthDay(day))
+	}
+	for _, wd := range rule.Weekdays {
+		items = append(items, e.nthWeekday(wd))
+	}
+
+	months := "every month"
+	if len(rule.Months) > 0 {
+		months = e.months(rule.Months)
+	}
+	return "on " + joinWords(items, "and") + " of " + months
+}
+
+func (english) yearly(rule Yearly) string {
+	text := "every year"
+	if rule.interval() > 1 {
+		text = "every " + strconv.Itoa(rule.interval()) + " years"
+	}
+
+	if len(rule.Dates) > 0 {
+		dates := make([]string, len(rule.Dates))
+		for i, date := range rule.Dates {
+			dates[i] = time.Month(date.Month).String() + " " + strconv.Itoa(date.Day)
+		}
+		text += " on " + joinWords(dates, "and")
+	}
+
+	switch {
+	case rule.Leap == LeapFeb28:
+		text += ", on February 28 in common years"
+	case rule.Leap == LeapMar1:
+		text += ", on March 1 in common years"
+	case len(rule.Dates) == 1 && rule.hasLeapDay():
+		text += ", only in leap years"
+	case rule.Leap == LeapOnly || rule.hasLeapDay():
+		text += ", February 29 only in leap years"
+	}
+	return text
+}
+
+func (english) relative(rule Relative) string {
+	units := map[string]string{"d": "day", "w": "week", "m": "month", "y": "year"}
+	period := units[rule.Unit]
+	if rule.N > 1 {
+		period += "s"
+	}
+	return strconv.Itoa(rule.N) + " " + period + " after completion"
+}
+
+func (english) intraday(rule Intraday) string {
+	unit := map[string]string{"h": "hour", "min": "minute"}[rule.Unit]
+	text := "every " + unit
+	if rule.N > 1 {
+		text = "every " + strconv.Itoa(rule.N) + " " + unit + "s"
+	}
+	if rule.Window {
+		text += " from " + clockString(rule.From) + " to " + clockString(rule.To)
+	}
+	return text
+}
+
+func (english) review() string {
+	return "spaced repetition by recall grade"
+}
+
+func (e english) rrule(rule RRule) string {
+	units := map[Frequency]string{FreqDaily: "day", FreqWeekly: "week", FreqMonthly: "month", FreqYearly: "year"}
+	text := "every " + units[rule.Freq]
+	if rule.Interval > 1 {
+		text = "every " + strconv.Itoa(rule.Interval) + " " + units[rule.Freq] + "s"
+	}
+
+	if len(rule.ByDay) > 0 {
+		if hasOrdinals(rule.ByDay) {
+			items := make([]string, len(rule.ByDay))
+			for i, wd := range rule.ByDay {
+				items[i] = e.nthWeekday(wd)
+			}
+			text += " on " + joinWords(items, "and")
+		} else {
+			text += " on " + e.weekdays(weekdayNumsISO(rule.ByDay))
+		}
+	}
+	if len(rule.ByMonthDay) > 0 {
+		items := make([]string, len(rule.ByMonthDay))
+		for i, day := range rule.ByMonthDay {
+			items[i] = e.monthDay(day)
+		}
+		text += " on " + joinWords(items, "and") + " of the month"
+	}
+	if len(rule.ByMonth) > 0 {
+		text += " in " + e.months(rule.ByMonth)
+	}
+	if len(rule.BySetPos) > 0 {
+		items := make([]string, len(rule.BySetPos))
+		for i, pos := range rule.BySetPos {
+			items[i] = e.setPos(pos)
+		}
+		text += ", only the " + joinWords(items, "and") + " in each " + units[rule.Freq]
+	}
+	return text + e.limits(rule.Until, rule.Count)
+}
+
+func (english) setPos(pos int) string {
+	switch {
+	case pos == -1:
+		return "last"
+	case pos == -2:
+		return "second to last"
+	case pos < 0:
+		return enOrdinal(-pos) + " to last"
+	}
+	return enOrdinal(pos)
+}
+
+func (e english) cron(rule Cron) string {
+	minute, hour := newCronField(rule.Minute, 0, 59), newCronField(rule.Hour, 0, 23)
+	when := e.cronDays(rule)
+
+	if times := cronTimes(minute, hour); times != nil {
+		if when == "" {
+			when = "every day"
+		}
+		return when + " at " + joinWords(times, "and")
+	}
+
+	var text string
+	switch {
+	case minute.all:
+		text = "every minute"
+	case minute.step > 0:
+		text = "every " + strconv.Itoa(minute.step) + " minutes"
+	default:
+		text = "at minute"
+		if len(minute.values) > 1 {
+			text += "s"
+		}
+		text += " " + joinWords(minute.items(), "and")
+		if hour.all {
+			text += " past every hour"
+		}
+	}
+	switch {
+	case hour.step > 0:
+		text += " every " + strconv.Itoa(hour.step) + " hours"
+	case hour.list():
+		text += " during hours " + joinWords(hour.items(), "and")
+	}
+
+	if when != "" {
+		text += " " + when
+	}
+	return text
+}
+
+// cronDays describes the days of a cron rule, or returns an empty string when
+// it runs every day.
+func (e english) cronDays(rule Cron) string {
+	var items []string
+	if day := newCronField(rule.Day, 1, 31); !day.all {
+		switch {
+		case len(day.values) == 1:
+			items = append(items, "on "+e.monthDay(day.values[0]))
+		case day.step > 0:
+			items = append(items, "every "+enOrdinal(day.step)+" day of the month")
+		default:
+			items = append(items, "on days "+joinWords(day.items(), "and"))
+		}
+	}
+	if days := cronWeekdays(rule); len(days) < 7 {
+		switch {
+		case isWorkweek(days):
+			items = append(items, "on weekdays")
+		case isWeekend(days):
+			items = append(items, "on weekends")
+		default:
+			items = append(items, "on "+e.weekdays(days))
+		}
+	}
+
+	join := " or "
+	if rule.dayAny || rule.weekdayAny {
+		join = " and "
+	}
+	text := strings.Join(items, join)
+	if month := newCronField(rule.Month, 1, 12); !month.all {
+		if text == "" {
+			text = "every day"
+		}
+		text += " in " + e.months(month.values)
+	}
+	return text
+}
+
+func (english) business(adjust Adjustment) string {
+	switch adjust {
+	case NextBusinessDay:
+		return ", moved to the next business day"
+	case PrevBusinessDay:
+		return ", moved to the previous business day"
+	default:
+		return ", business days only"
+	}
+}
+
+func (english) limits(until time.Time, count int) string {
+	var text string
+	switch {
+	case count == 1:
+		text += ", 1 time"
+	case count > 1:
+		text += ", " + strconv.Itoa(count) + " times"
+	}
+	if !until.IsZero() {
+		text += ", until " + until.Format("2006-01-02")
+	}
+	return text
+}
+
+func (english) union(texts []string) string {
+	return strings.Join(texts, "; and also ")
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDescribeRule(t *testing.T) {
+	tests := []struct {
+		repeat string
+		ru     string
+		en     string
+	}{
+		{"m 1,-1 2,8",
+			"1-го и в последний день февраля и августа",
+			"on the 1st and the last day of February and August"},
+		{"c 0 9 * * *", "ежедневно в 09:00", "every day at 09:00"},
+		{"c 30 8,18 * * 1-5", "по будням в 08:30 и 18:30", "on weekdays at 08:30 and 18:30"},
+		{"c 5 4 * * 0", "по воскресеньям в 04:05", "on Sunday at 04:05"},
+		{"c * * * * *", "каждую минуту", "every minute"},
+		{"c */15 * * * *", "каждые 15 минут", "every 15 minutes"},
+		{"c 15,45 * * * *", "в 15 и 45 минут каждого часа", "at minutes 15 and 45 past every hour"},
+		{"c 0 */2 * * *", "в 0 минут каждые 2 часа", "at minute 0 every 2 hours"},
+		{"c 0 9-17 * * 6,0", "в 0 минут в часы 9–17 по выходным", "at minute 0 during hours 9–17 on weekends"},
+		{"c 0 0 1 * *", "1-го числа в 00:00", "on the 1st at 00:00"},
+		{"c 0 9 1-5,10 * *", "по числам 1–5 и 10 в 09:00", "on days 1–5 and 10 at 09:00"},
+		{"c 0 9 */2 * *", "каждый 2-й день месяца в 09:00", "every 2nd day of the month at 09:00"},
+		{"c 0 12 13 * 5", "13-го числа или по пятницам в 12:00", "on the 13th or on Friday at 12:00"},
+		{"c 0 9 * 1,7 *", "каждый день в январе и июле в 09:00", "every day in January and July at 09:00"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20241231",
+			"каждые 2 недели по понедельникам и четвергам, до 31.12.2024",
+			"every 2 weeks on Monday and Thursday, until 2024-12-31"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			"ежемесячно в последнюю пятницу, 3 раза",
+			"every month on the last Friday, 3 times"},
+		{"FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO",
+			"ежегодно в последний понедельник в мае",
+			"every year on the last Monday in May"},
+		{"FREQ=MONTHLY;BYDAY=SA,SU;BYSETPOS=1,-1",
+			"ежемесячно по субботам и воскресеньям, только 1-й и последний по счёту",
+			"every month on Saturday and Sunday, only the 1st and last in each month"},
+		{"d 7 next-bd",
+			"каждые 7 дней, с переносом на следующий рабочий день",
+			"every 7 days, moved to the next business day"},
+		{"w 1,3 prev-bd",
+			"по понедельникам и средам, с переносом на предыдущий рабочий день",
+			"every Monday and Wednesday, moved to the previous business day"},
+		{"m 15 bd",
+			"15-го каждого месяца, только в рабочие дни",
+			"on the 15th of every month, business days only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			for lang, want := range map[string]string{LangRussian: tt.ru, LangEnglish: tt.en} {
+				got, err := DescribeRule(tt.repeat, lang)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s: got %q, want %q", lang, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeLimited(t *testing.T) {
+	rule, err := ParseRule("w 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule Rule
+		ru   string
+		en   string
+	}{
+		{WithLimits(rule, until, 0), "по понедельникам, до 31.12.2024", "every Monday, until 2024-12-31"},
+		{WithLimits(rule, time.Time{}, 5), "по понедельникам, 5 раз", "every Monday, 5 times"},
+		{WithLimits(rule, time.Time{}, 1), "по понедельникам, 1 раз", "every Monday, 1 time"},
+		{WithLimits(rule, until, 2), "по понедельникам, 2 раза, до 31.12.2024", "every Monday, 2 times, until 2024-12-31"},
+	}
+
+	for _, tt := range tests {
+		for lang, want := range map[string]string{LangRussian: tt.ru, LangEnglish: tt.en} {
+			got, err := Describe(tt.rule, lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s: got %q, want %q", lang, got, want)
+			}
+		}
+	}
+}
+
+func TestDescribeUnknownLanguage(t *testing.T) {
+	if _, err := DescribeRule("d 1", "de"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
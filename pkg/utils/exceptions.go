@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"time"
+)
+
+const (
+	maxExceptionSkips = 10000
+)
+
+// Excluding skips the occurrences of a rule that fall on exception dates.
+// Exceptions belong to a task rather than to its repeat string, so String
+// returns the wrapped rule unchanged.
+type Excluding struct {
+	Rule  Rule
+	Dates map[string]bool
+}
+
+func WithExceptions(rule Rule, dates []time.Time) Rule {
+	if len(dates) == 0 {
+		return rule
+	}
+
+	set := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		set[date.Format(DateFormat)] = true
+	}
+	return Excluding{Rule: rule, Dates: set}
+}
+
+func (r Excluding) Next(after time.Time) time.Time {
+	next := after
+	for i := 0; i < maxExceptionSkips; i++ {
+		next = r.Rule.Next(next)
+		if next.IsZero() || !r.Dates[next.Format(DateFormat)] {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func (r Excluding) Bounds() (time.Time, int) {
+	if bounded, ok := r.Rule.(Bounded); ok {
+		return bounded.Bounds()
+	}
+	return time.Time{}, 0
+}
+
+func (r Excluding) String() string {
+	return r.Rule.String()
+}
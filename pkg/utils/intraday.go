@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxIntradayHours   = 24
+	maxIntradayMinutes = 24 * 60
+)
+
+// Intraday repeats several times a day, every N hours (h N) or minutes
+// (min N). Without a window the occurrences follow the start time by the
+// elapsed interval. With a window such as 09:00-18:00 they restart at the
+// beginning of the window every day and stop at its end.
+type Intraday struct {
+	Unit   string
+	N      int
+	Window bool
+	From   int
+	To     int
+}
+
+func parseIntraday(parts []string) (Rule, error) {
+	kind := parts[0]
+	if err := checkParts(parts, 2, 3, "invalid repeat format for "+kind); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	max := maxIntradayMinutes
+	if kind == "h" {
+		max = maxIntradayHours
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n < 1 || n > max {
+		return nil, newRuleError(kind, CodeInterval, parts[1], offsets[1], "invalid interval: "+parts[1])
+	}
+
+	rule := Intraday{Unit: kind, N: n}
+	if len(parts) == 3 {
+		from, to, ok := strings.Cut(parts[2], "-")
+		fromTime, err1 := time.Parse(TimeFormat, from)
+		toTime, err2 := time.Parse(TimeFormat, to)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, newRuleError(kind, CodeFormat, parts[2], offsets[2],
+				"invalid window, expected HH:MM-HH:MM: "+parts[2])
+		}
+
+		rule.Window = true
+		rule.From = fromTime.Hour()*60 + fromTime.Minute()
+		rule.To = toTime.Hour()*60 + toTime.Minute()
+		if rule.From >= rule.To {
+			return nil, newRuleError(kind, CodeRange, parts[2], offsets[2],
+				"window must end after it starts: "+parts[2])
+		}
+	}
+
+	return rule, nil
+}
+
+func (r Intraday) step() time.Duration {
+	if r.Unit == "h" {
+		return time.Duration(r.N) * time.Hour
+	}
+	return time.Duration(r.N) * time.Minute
+}
+
+// Next adds the interval to after, or with a window moves to the next
+// multiple of the interval counted from the window's start, on the next day
+// once the window is over. Windows follow the wall clock.
+func (r Intraday) Next(after time.Time) time.Time {
+	if !r.Window {
+		return after.Add(r.step())
+	}
+
+	step := int(r.step() / time.Minute)
+	year, month, day := after.Date()
+	minute := after.Hour()*60 + after.Minute()
+
+	next := r.From
+	if minute >= r.From {
+		next = r.From + ((minute-r.From)/step+1)*step
+	}
+	if next > r.To {
+		day, next = day+1, r.From
+	}
+	return wallClock(year, month, day, next/60, next%60, after.Location())
+}
+
+func (r Intraday) String() string {
+	text := r.Unit + " " + strconv.Itoa(r.N)
+	if r.Window {
+		text += " " + clockString(r.From) + "-" + clockString(r.To)
+	}
+	return text
+}
+
+func clockString(minute int) string {
+	return time.Date(0, 1, 1, minute/60, minute%60, 0, 0, time.UTC).Format(TimeFormat)
+}
+
+// IsSubDaily reports whether rule repeats within a day.
+func IsSubDaily(rule Rule) bool {
+	return len(intradayRules(rule)) > 0
+}
+
+// FirstTime returns the time of day in TimeFormat at which a rule repeating
+// within a day, or a cron rule, starts a task that was given only a date.
+func FirstTime(rule Rule) (string, bool) {
+	minute, ok := firstMinute(rule)
+	return clockString(minute), ok
+}
+
+func firstMinute(rule Rule) (int, bool) {
+	switch r := rule.(type) {
+	case Intraday:
+		return r.From, true
+	case Cron:
+		return r.firstMinute()
+	case Union:
+		first, found := 0, false
+		for _, rule := range r.Rules {
+			if minute, ok := firstMinute(rule); ok && (!found || minute < first) {
+				first, found = minute, true
+			}
+		}
+		return first, found
+	case BusinessDay:
+		return firstMinute(r.Rule)
+	case Clocked:
+		return firstMinute(r.Rule)
+	case Limited:
+		return firstMinute(r.Rule)
+	case Excluding:
+		return firstMinute(r.Rule)
+	}
+	return 0, false
+}
+
+// intradayRules returns the Intraday rules under the modifiers of rule.
+func intradayRules(rule Rule) []Intraday {
+	switch r := rule.(type) {
+	case Intraday:
+		return []Intraday{r}
+	case Union:
+		var rules []Intraday
+		for _, rule := range r.Rules {
+			rules = append(rules, intradayRules(rule)...)
+		}
+		return rules
+	case BusinessDay:
+		return intradayRules(r.Rule)
+	case Clocked:
+		return intradayRules(r.Rule)
+	case Limited:
+		return intradayRules(r.Rule)
+	case Excluding:
+		return intradayRules(r.Rule)
+	}
+	return nil
+}
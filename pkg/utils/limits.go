@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"time"
+)
+
+// Limited stops a rule at an end date or after a number of occurrences, on
+// top of any bounds the rule carries itself.
+type Limited struct {
+	Rule  Rule
+	Until time.Time
+	Count int
+}
+
+func WithLimits(rule Rule, until time.Time, count int) Rule {
+	if until.IsZero() && count <= 0 {
+		return rule
+	}
+	return Limited{Rule: rule, Until: until, Count: count}
+}
+
+func (r Limited) Next(after time.Time) time.Time {
+	return r.Rule.Next(after)
+}
+
+func (r Limited) Bounds() (time.Time, int) {
+	until, count := r.Until, r.Count
+	if bounded, ok := r.Rule.(Bounded); ok {
+		ruleUntil, ruleCount := bounded.Bounds()
+		if until.IsZero() || (!ruleUntil.IsZero() && ruleUntil.Before(until)) {
+			until = ruleUntil
+		}
+		if count <= 0 || (ruleCount > 0 && ruleCount < count) {
+			count = ruleCount
+		}
+	}
+	return until, count
+}
+
+func (r Limited) String() string {
+	return r.Rule.String()
+}
@@ -0,0 +1,510 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PhraseError reports the word of a natural-language phrase that could not be
+// understood. Offset is counted in characters from the start of the phrase.
+type PhraseError struct {
+	Token  string
+	Offset int
+	Reason string
+}
+
+func (e *PhraseError) Error() string {
+	if e.Token == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %q at %d", e.Reason, e.Token, e.Offset)
+}
+
+type phraseUnit int
+
+const (
+	unitNone phraseUnit = iota
+	unitMinute
+	unitHour
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+type phraseToken struct {
+	text   string
+	offset int
+}
+
+var (
+	phraseStopWords = map[string]bool{
+		"every": true, "each": true, "on": true, "the": true, "of": true, "in": true, "at": true,
+		"and": true, "a": true, "an": true, "once": true, "day's": true,
+		"каждый": true, "каждую": true, "каждое": true, "каждые": true, "каждого": true,
+		"каждой": true, "каждом": true, "каждых": true, "по": true, "в": true, "во": true,
+		"и": true, "раз": true, "на": true, "after": true, "после": true,
+	}
+	// phraseCompletion marks a period counted from the completion of the
+	// task: "через 7 дней после выполнения", "7 days after completion".
+	phraseCompletion = map[string]bool{
+		"completion": true, "done": true, "finishing": true, "выполнения": true, "завершения": true,
+	}
+	phraseUnits = map[string]phraseUnit{
+		"minute": unitMinute, "minutes": unitMinute, "min": unitMinute, "mins": unitMinute,
+		"минута": unitMinute, "минуту": unitMinute, "минуты": unitMinute, "минут": unitMinute,
+		"hour": unitHour, "hours": unitHour, "час": unitHour, "часа": unitHour, "часов": unitHour,
+		"day": unitDay, "days": unitDay, "день": unitDay, "дня": unitDay, "дней": unitDay,
+		"дням": unitDay, "сутки": unitDay, "суток": unitDay,
+		"week": unitWeek, "weeks": unitWeek, "неделя": unitWeek, "неделю": unitWeek,
+		"недели": unitWeek, "недель": unitWeek, "неделе": unitWeek,
+		"month": unitMonth, "months": unitMonth, "месяц": unitMonth, "месяца": unitMonth,
+		"месяцев": unitMonth, "месяце": unitMonth,
+		"year": unitYear, "years": unitYear, "год": unitYear, "года": unitYear, "лет": unitYear,
+		"году": unitYear,
+	}
+	phraseAdverbs = map[string]phraseUnit{
+		"hourly": unitHour, "ежечасно": unitHour,
+		"daily": unitDay, "ежедневно": unitDay,
+		"weekly": unitWeek, "еженедельно": unitWeek,
+		"monthly": unitMonth, "ежемесячно": unitMonth,
+		"yearly": unitYear, "annually": unitYear, "ежегодно": unitYear,
+	}
+	// phraseWeekdayGroups are words that stand for several weekdays and may be
+	// followed by a day unit, as in "по рабочим дням".
+	phraseWeekdayGroups = map[string][]int{
+		"weekdays": {1, 2, 3, 4, 5}, "weekday": {1, 2, 3, 4, 5}, "workdays": {1, 2, 3, 4, 5}, "business": {1, 2, 3, 4, 5},
+		"будни": {1, 2, 3, 4, 5}, "будням": {1, 2, 3, 4, 5}, "будним": {1, 2, 3, 4, 5},
+		"рабочим": {1, 2, 3, 4, 5}, "рабочие": {1, 2, 3, 4, 5},
+		"weekends": {6, 7}, "weekend": {6, 7}, "выходные": {6, 7}, "выходным": {6, 7},
+	}
+	enWeekdayWords = map[string]int{
+		"monday": 1, "mondays": 1, "mon": 1,
+		"tuesday": 2, "tuesdays": 2, "tue": 2, "tues": 2,
+		"wednesday": 3, "wednesdays": 3, "wed": 3,
+		"thursday": 4, "thursdays": 4, "thu": 4, "thur": 4, "thurs": 4,
+		"friday": 5, "fridays": 5, "fri": 5,
+		"saturday": 6, "saturdays": 6, "sat": 6,
+		"sunday": 7, "sundays": 7, "sun": 7,
+		"пн": 1, "вт": 2, "ср": 3, "чт": 4, "пт": 5, "сб": 6, "вс": 7,
+	}
+	ruWeekdayStems = []string{"", "понедельн", "вторник", "сред", "четверг", "пятниц", "суббот", "воскресен"}
+	enMonthWords   = map[string]int{
+		"january": 1, "jan": 1, "february": 2, "feb": 2, "march": 3, "mar": 3,
+		"april": 4, "apr": 4, "may": 5, "june": 6, "jun": 6, "july": 7, "jul": 7,
+		"august": 8, "aug": 8, "september": 9, "sep": 9, "sept": 9,
+		"october": 10, "oct": 10, "november": 11, "nov": 11, "december": 12, "dec": 12,
+		"май": 5, "мая": 5, "мае": 5,
+	}
+	ruMonthStems = []string{"", "январ", "феврал", "март", "апрел", "", "июн", "июл", "август", "сентябр", "октябр", "ноябр", "декабр"}
+	// phraseOrdinals are checked after weekdays, so "второй" does not shadow
+	// "вторник" and "пятый" does not shadow "пятница".
+	phraseOrdinals = map[string]int{
+		"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5,
+		"last": -1, "penultimate": -2,
+	}
+	ruOrdinalStems = []struct {
+		stem string
+		n    int
+	}{
+		{"предпоследн", -2}, {"последн", -1}, {"перв", 1}, {"втор", 2}, {"трет", 3},
+		{"четверт", 4}, {"пят", 5},
+	}
+	// phraseOrdinalSuffixes turn the preceding number into an ordinal:
+	// "1st", "2-й", "15-го".
+	phraseOrdinalSuffixes = map[string]bool{
+		"st": true, "nd": true, "rd": true, "th": true, "й": true, "го": true, "ое": true, "ой": true,
+	}
+	phraseDayMarkers = map[string]bool{"числа": true, "число": true, "числам": true}
+)
+
+// phrase accumulates what the words of a natural-language repeat say.
+type phrase struct {
+	unit      phraseUnit
+	interval  int
+	weekdays  []int
+	nth       []WeekdayNum
+	days      []int
+	months    []int
+	numbers   []phraseToken
+	ordinal   int
+	ordinalAt phraseToken
+	skipDay   bool
+	relative  bool
+	counted   bool
+}
+
+// ParseNatural converts a Russian or English phrase such as "каждый вторник",
+// "every 2 weeks on monday" or "15 числа каждого месяца" into the canonical
+// repeat rule. A month without a day, as in "каждый месяц", repeats on the
+// day of start, or falls back to an RRULE when start is zero. Failures are
+// reported as *PhraseError.
+func ParseNatural(text string, start time.Time) (string, error) {
+	tokens := splitPhrase(text)
+	if len(tokens) == 0 {
+		return "", &PhraseError{Reason: "phrase is empty"}
+	}
+
+	p := phrase{}
+	for _, tok := range tokens {
+		if err := p.add(tok); err != nil {
+			return "", err
+		}
+	}
+	if err := p.flush(); err != nil {
+		return "", err
+	}
+
+	rule, err := p.rule(start)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := ParseRule(rule.String())
+	if err != nil {
+		return "", &PhraseError{Reason: err.Error()}
+	}
+	return parsed.String(), nil
+}
+
+// splitPhrase lowercases the phrase and splits it into runs of letters and
+// runs of digits, so "15th" and "15-го" both become a number and a suffix.
+func splitPhrase(text string) []phraseToken {
+	var tokens []phraseToken
+	var word []rune
+	start, digits := 0, false
+
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, phraseToken{text: string(word), offset: start})
+			word = word[:0]
+		}
+	}
+
+	for i, r := range []rune(strings.ToLower(text)) {
+		if r == 'ё' {
+			r = 'е'
+		}
+		isDigit, isLetter := unicode.IsDigit(r), unicode.IsLetter(r) || r == '\''
+		if !isDigit && !isLetter {
+			flush()
+			continue
+		}
+		if len(word) > 0 && isDigit != digits {
+			flush()
+		}
+		if len(word) == 0 {
+			start, digits = i, isDigit
+		}
+		word = append(word, r)
+	}
+	flush()
+	return tokens
+}
+
+func (p *phrase) add(tok phraseToken) error {
+	word := tok.text
+
+	if _, err := strconv.Atoi(word); err == nil {
+		if err = p.flushOrdinal(); err != nil {
+			return err
+		}
+		p.numbers = append(p.numbers, tok)
+		return nil
+	}
+
+	if phraseOrdinalSuffixes[word] && len(p.numbers) > 0 {
+		last := p.numbers[len(p.numbers)-1]
+		p.numbers = p.numbers[:len(p.numbers)-1]
+		if err := p.numbersToDays(); err != nil {
+			return err
+		}
+		n, _ := strconv.Atoi(last.text)
+		p.ordinal, p.ordinalAt = n, last
+		return nil
+	}
+
+	if phraseDayMarkers[word] {
+		if p.ordinal != 0 {
+			return p.flushOrdinal()
+		}
+		if len(p.numbers) == 0 {
+			return &PhraseError{Token: word, Offset: tok.offset, Reason: "day of month expected before"}
+		}
+		return p.numbersToDays()
+	}
+
+	if day, ok := phraseWeekday(word); ok {
+		if err := p.numbersToDays(); err != nil {
+			return err
+		}
+		if p.ordinal != 0 {
+			if p.ordinal < -maxMonthlyOrdinal || p.ordinal > maxMonthlyOrdinal {
+				return &PhraseError{Token: p.ordinalAt.text, Offset: p.ordinalAt.offset, Reason: "invalid weekday ordinal"}
+			}
+			p.nth = append(p.nth, WeekdayNum{N: p.ordinal, Day: time.Weekday(day % 7)})
+			p.ordinal = 0
+			return nil
+		}
+		p.weekdays = append(p.weekdays, day)
+		return nil
+	}
+
+	if month, ok := phraseMonth(word); ok {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.months = append(p.months, month)
+		return nil
+	}
+
+	if n, ok := phraseOrdinal(word); ok {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.ordinal, p.ordinalAt = n, tok
+		return nil
+	}
+
+	if days, ok := phraseWeekdayGroups[word]; ok {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.weekdays = append(p.weekdays, days...)
+		p.skipDay = true
+		return nil
+	}
+
+	if unit, ok := phraseUnits[word]; ok {
+		if unit == unitDay && p.skipDay {
+			p.skipDay = false
+			return nil
+		}
+		if unit == unitDay && p.ordinal != 0 {
+			return p.flushOrdinal()
+		}
+		if err := p.flushOrdinal(); err != nil {
+			return err
+		}
+
+		interval := p.interval
+		switch len(p.numbers) {
+		case 0:
+		case 1:
+			n := p.numbers[0]
+			interval, _ = strconv.Atoi(n.text)
+			if interval < 1 {
+				return &PhraseError{Token: n.text, Offset: n.offset, Reason: "period must be positive"}
+			}
+			p.numbers = nil
+			p.counted = true
+		default:
+			bad := p.numbers[1]
+			return &PhraseError{Token: bad.text, Offset: bad.offset, Reason: "unexpected number"}
+		}
+		return p.setUnit(unit, interval, tok)
+	}
+
+	if unit, ok := phraseAdverbs[word]; ok {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		return p.setUnit(unit, p.interval, tok)
+	}
+
+	if phraseCompletion[word] {
+		p.relative = true
+		return nil
+	}
+
+	switch word {
+	case "other", "через":
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.interval = 2
+		return nil
+	}
+
+	if phraseStopWords[word] {
+		return nil
+	}
+
+	return &PhraseError{Token: word, Offset: tok.offset, Reason: "unknown word"}
+}
+
+// setUnit sets the period of the phrase; an interval of 0 means none was
+// given.
+func (p *phrase) setUnit(unit phraseUnit, interval int, tok phraseToken) error {
+	if p.unit != unitNone && (p.unit != unit || p.interval != interval) {
+		return &PhraseError{Token: tok.text, Offset: tok.offset, Reason: "conflicting period"}
+	}
+	p.unit, p.interval = unit, interval
+	return nil
+}
+
+// flush resolves the pending numbers and ordinal as days of the month.
+func (p *phrase) flush() error {
+	if err := p.flushOrdinal(); err != nil {
+		return err
+	}
+	return p.numbersToDays()
+}
+
+func (p *phrase) flushOrdinal() error {
+	if p.ordinal == 0 {
+		return nil
+	}
+	n := p.ordinal
+	p.ordinal = 0
+	if n == 0 || n < -2 || n > 31 {
+		return &PhraseError{Token: p.ordinalAt.text, Offset: p.ordinalAt.offset, Reason: "invalid day of month"}
+	}
+	p.days = append(p.days, n)
+	return nil
+}
+
+func (p *phrase) numbersToDays() error {
+	for _, tok := range p.numbers {
+		n, _ := strconv.Atoi(tok.text)
+		if n < 1 || n > 31 {
+			return &PhraseError{Token: tok.text, Offset: tok.offset, Reason: "invalid day of month"}
+		}
+		p.days = append(p.days, n)
+	}
+	p.numbers = nil
+	return nil
+}
+
+func (p *phrase) rule(start time.Time) (Rule, error) {
+	// No number was given, since setUnit rejects 0.
+	interval := p.interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	// "каждую вторую среду" skips weeks unless a month is mentioned, which
+	// makes it "во вторую среду каждого месяца".
+	if n, ok := p.weekInterval(); ok {
+		for _, wd := range p.nth {
+			p.weekdays = append(p.weekdays, (int(wd.Day)+6)%7+1)
+		}
+		p.nth, interval = nil, n
+	}
+	hasDays := len(p.days) > 0 || len(p.nth) > 0
+
+	if p.relative {
+		units := map[phraseUnit]string{unitDay: "d", unitWeek: "w", unitMonth: "m", unitYear: "y"}
+		if p.unit == unitNone || p.unit == unitHour || p.unit == unitMinute || hasDays || len(p.weekdays) > 0 || len(p.months) > 0 {
+			return nil, &PhraseError{Reason: "a period after completion must be in days, weeks, months or years"}
+		}
+		// "через день после выполнения" is one day after, not every other day.
+		if !p.counted {
+			interval = 1
+		}
+		return Relative{Unit: units[p.unit], N: interval}, nil
+	}
+
+	switch {
+	case len(p.weekdays) > 0:
+		if hasDays || len(p.months) > 0 {
+			return nil, &PhraseError{Reason: "weekdays cannot be combined with days of month or months"}
+		}
+		if p.unit != unitNone && p.unit != unitWeek {
+			return nil, &PhraseError{Reason: "weekdays require a weekly period"}
+		}
+		return Weekly{Weekdays: p.weekdays, Interval: interval}, nil
+
+	case hasDays:
+		switch {
+		case p.unit == unitMinute || p.unit == unitHour || p.unit == unitDay || p.unit == unitWeek:
+			return nil, &PhraseError{Reason: "days of month require a monthly or yearly period"}
+		case p.unit == unitYear && len(p.months) == 0:
+			return nil, &PhraseError{Reason: "yearly repeat needs a month"}
+		case interval > 1:
+			freq := FreqMonthly
+			if p.unit == unitYear {
+				freq = FreqYearly
+			}
+			return RRule{Freq: freq, Interval: interval, ByMonthDay: p.days, ByDay: p.nth, ByMonth: p.months}, nil
+		}
+		return Monthly{Days: p.days, Weekdays: p.nth, Months: p.months}, nil
+
+	case len(p.months) > 0:
+		return nil, &PhraseError{Reason: "months require a day of month"}
+	}
+
+	switch p.unit {
+	case unitMinute:
+		return Intraday{Unit: "min", N: interval}, nil
+	case unitHour:
+		return Intraday{Unit: "h", N: interval}, nil
+	case unitDay:
+		return Daily{Days: interval}, nil
+	case unitWeek:
+		return Daily{Days: 7 * interval}, nil
+	case unitMonth:
+		if interval == 1 && !start.IsZero() {
+			return Monthly{Days: []int{start.Day()}}, nil
+		}
+		return RRule{Freq: FreqMonthly, Interval: interval}, nil
+	case unitYear:
+		return Yearly{Interval: interval}, nil
+	}
+	return nil, &PhraseError{Reason: "no repeat period found"}
+}
+
+// weekInterval reports whether the ordinal weekdays of the phrase count
+// weeks rather than weekdays of a month: they share an ordinal from 2 on and
+// neither a month nor another period is mentioned.
+func (p *phrase) weekInterval() (int, bool) {
+	if len(p.nth) == 0 || p.unit != unitNone || p.interval != 0 || len(p.days) > 0 || len(p.months) > 0 {
+		return 0, false
+	}
+	n := p.nth[0].N
+	for _, wd := range p.nth {
+		if wd.N != n {
+			return 0, false
+		}
+	}
+	return n, n >= 2
+}
+
+func phraseWeekday(word string) (int, bool) {
+	if day, ok := enWeekdayWords[word]; ok {
+		return day, true
+	}
+	for day, stem := range ruWeekdayStems {
+		if stem != "" && strings.HasPrefix(word, stem) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func phraseMonth(word string) (int, bool) {
+	if month, ok := enMonthWords[word]; ok {
+		return month, true
+	}
+	for month, stem := range ruMonthStems {
+		if stem != "" && strings.HasPrefix(word, stem) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+func phraseOrdinal(word string) (int, bool) {
+	if n, ok := phraseOrdinals[word]; ok {
+		return n, true
+	}
+	for _, ord := range ruOrdinalStems {
+		if strings.HasPrefix(word, ord.stem) {
+			return ord.n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNatural(t *testing.T) {
+	start := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text  string
+		start time.Time
+		want  string
+	}{
+		{"каждый месяц", start, "m 15"},
+		{"monthly", start, "m 15"},
+		{"каждый месяц", time.Time{}, "FREQ=MONTHLY"},
+		{"every 3 months", start, "FREQ=MONTHLY;INTERVAL=3"},
+		{"каждую вторую среду", start, "w 3 2"},
+		{"каждую третью пятницу", start, "w 5 3"},
+		{"every second monday and thursday", start, "w 1,4 2"},
+		{"во вторую среду каждого месяца", start, "m 2we"},
+		{"каждую последнюю пятницу", start, "m -1fr"},
+		{"каждый первый понедельник", start, "m 1mo"},
+		{"каждый день", start, "d 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseNatural(tt.text, tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseNatural = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNaturalZeroPeriod(t *testing.T) {
+	tests := []struct {
+		text   string
+		offset int
+	}{
+		{"every 0 days", 6},
+		{"каждые 0 недель", 7},
+		{"через 0 дней после выполнения", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := ParseNatural(tt.text, time.Time{})
+			phraseErr, ok := err.(*PhraseError)
+			if !ok {
+				t.Fatalf("err = %v, want a *PhraseError", err)
+			}
+			if phraseErr.Token != "0" || phraseErr.Offset != tt.offset {
+				t.Errorf("error at %q %d, want \"0\" %d", phraseErr.Token, phraseErr.Offset, tt.offset)
+			}
+		})
+	}
+}
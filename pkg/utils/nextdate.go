@@ -2,8 +2,6 @@ package utils
 
 import (
 	"errors"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -11,161 +9,61 @@ const (
 	DateFormat = "20060102"
 )
 
-func NextDate(now time.Time, dstart string, repeat string) (string, error) {
-	if repeat == "" {
-		return "", errors.New("repeat is empty")
-	}
+var ErrNoOccurrences = errors.New("repeat has no more occurrences")
 
-	startDate, err := time.Parse(DateFormat, dstart)
+func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+	rule, err := ParseRule(repeat)
 	if err != nil {
 		return "", err
 	}
 
-	switch {
-	case strings.HasPrefix(repeat, "d "):
-		return dailyRule(now, startDate, repeat)
-	case strings.HasPrefix(repeat, "y"):
-		return yearlyRule(now, startDate)
-	case strings.HasPrefix(repeat, "w "):
-		return weeklyRule(now, repeat)
-	case strings.HasPrefix(repeat, "m "):
-		return monthlyRule(now, startDate, repeat)
-	default:
-		return "", errors.New("invalid repeat")
-	}
-}
-
-func dailyRule(now, startDate time.Time, repeat string) (string, error) {
-	days, err := parseDays(repeat)
+	startDate, timed, err := ParseDateTime(dstart, now.Location())
 	if err != nil {
 		return "", err
 	}
 
-	for {
-		startDate = startDate.AddDate(0, 0, days)
-		if startDate.After(now) {
-			return startDate.Format(DateFormat), nil
-		}
-	}
-}
-
-func parseDays(repeat string) (int, error) {
-	parts := strings.Split(repeat, " ")
-	if len(parts) != 2 {
-		return 0, errors.New("invalid repeat format for d")
-	}
-
-	days, err := strconv.Atoi(parts[1])
-	if err != nil || days < 0 || days > 400 {
-		return 0, errors.New("invalid days: " + parts[1])
-	}
-
-	return days, nil
-}
-
-func yearlyRule(now, startDate time.Time) (string, error) {
-	for {
-		startDate = startDate.AddDate(1, 0, 0)
-		if startDate.After(now) {
-			return startDate.Format(DateFormat), nil
-		}
-	}
-}
-
-func weeklyRule(now time.Time, repeat string) (string, error) {
-	weekdays, err := parseWeekdays(repeat)
-	if err != nil {
+	if rule, err = AnchorRule(rule, startDate); err != nil {
 		return "", err
 	}
 
-	for {
-		now = now.AddDate(0, 0, 1)
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7
-		}
-
-		for _, day := range weekdays {
-			if weekday == day {
-				return now.Format(DateFormat), nil
-			}
-		}
-	}
-}
-
-func parseWeekdays(repeat string) ([]int, error) {
-	parts := strings.Split(repeat, " ")
-	if len(parts) != 2 {
-		return nil, errors.New("invalid repeat format for w")
+	if first, ok := firstMinute(rule); ok && !timed {
+		year, month, day := startDate.Date()
+		startDate = wallClock(year, month, day, first/60, first%60, startDate.Location())
+		timed = true
 	}
 
-	partsWeek := strings.Split(parts[1], ",")
-	var weekdays []int
-	for _, part := range partsWeek {
-		day, err := strconv.Atoi(part)
-		if err != nil || day < 1 || day > 7 {
-			return nil, errors.New("invalid weekday: " + part)
-		}
-		weekdays = append(weekdays, day)
+	layout := DateFormat
+	if timed {
+		rule = WithClock(rule, startDate.Hour(), startDate.Minute())
+		layout = DateTimeFormat
 	}
 
-	return weekdays, nil
-}
-
-func monthlyRule(now, startDate time.Time, repeat string) (string, error) {
-	days, months, err := parseMonthsDays(repeat)
+	next, err := NextAfter(rule, startDate, now)
 	if err != nil {
 		return "", err
 	}
 
-	for {
-		year, month, day := startDate.Date()
-
-		if len(months) > 0 && !months[int(month)] {
-			startDate = startDate.AddDate(0, 1, -startDate.Day()+1)
-			continue
-		}
-
-		lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, startDate.Location()).Day()
-		for d := range days {
-			if (d > 0 && d == day) || (d == -1 && day == lastDay) || (d == -2 && day == lastDay-1) {
-				if startDate.After(now) {
-					return startDate.Format(DateFormat), nil
-				}
-			}
-		}
-
-		startDate = startDate.AddDate(0, 0, 1)
-	}
+	return next.Format(layout), nil
 }
 
-func parseMonthsDays(repeat string) (map[int]bool, map[int]bool, error) {
-	parts := strings.Split(repeat, " ")
-	if len(parts) < 2 || len(parts) > 3 {
-		return nil, nil, errors.New("invalid repeat format for m")
+// NextAfter returns the first occurrence of rule after now for a task that
+// started at start. Completion-relative rules count from now instead, keeping
+// the time of day of start.
+func NextAfter(rule Rule, start, now time.Time) (time.Time, error) {
+	if IsRelative(rule) {
+		now = now.In(start.Location())
+		start = time.Date(now.Year(), now.Month(), now.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
 	}
 
-	partsDays := strings.Split(parts[1], ",")
-	days := make(map[int]bool)
-	for _, part := range partsDays {
-		day, err := strconv.Atoi(part)
-		if err != nil || day == 0 || day < -2 || day > 31 {
-			return nil, nil, errors.New("invalid day: " + part)
+	it := newOccurrenceIter(rule, start)
+	it.skipTo(now)
+	for it.next() {
+		if it.date.After(now) {
+			return it.date, nil
 		}
-		days[day] = true
 	}
-
-	months := make(map[int]bool)
-	if len(parts) == 3 {
-		partsMonth := strings.Split(parts[2], ",")
-		for _, part := range partsMonth {
-			month, err := strconv.Atoi(part)
-			if err != nil || month < 1 || month > 12 {
-				return nil, nil, errors.New("invalid month: " + part)
-			}
-			months[month] = true
-		}
+	if it.limited {
+		return time.Time{}, ErrSearchLimit
 	}
-
-	return days, months, nil
+	return time.Time{}, ErrNoOccurrences
 }
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// stepOnly hides the skipper of a rule, so NextAfter has to walk every
+// occurrence from the start date as it did before.
+type stepOnly struct {
+	Rule
+}
+
+var benchRules = []string{"d 1", "d 7", "w 1,3,5", "w 2 3", "m 15,-1", "m 2tu", "y", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"}
+
+func benchmarkNextAfter(b *testing.B, wrap func(Rule) Rule) {
+	start := time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, time.January, 26, 0, 0, 0, 0, time.UTC)
+
+	for _, repeat := range benchRules {
+		rule, err := ParseRule(repeat)
+		if err != nil {
+			b.Fatal(err)
+		}
+		rule = wrap(rule)
+
+		b.Run(repeat, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NextAfter(rule, start, now); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNextAfterFarPast(b *testing.B) {
+	benchmarkNextAfter(b, func(rule Rule) Rule { return rule })
+}
+
+func BenchmarkNextAfterFarPastStepwise(b *testing.B) {
+	benchmarkNextAfter(b, func(rule Rule) Rule { return stepOnly{rule} })
+}
+
+func BenchmarkNextDateImpossible(b *testing.B) {
+	now := time.Date(2024, time.January, 26, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < b.N; i++ {
+		if _, err := NextDate(now, "20240101", "m 30,31 2"); err == nil {
+			b.Fatal("expected an error")
+		}
+	}
+}
+
+func TestSkipMatchesStepping(t *testing.T) {
+	now := time.Date(2024, time.January, 26, 0, 0, 0, 0, time.UTC)
+	rules := []string{
+		"d 3", "w 2,6 3", "m 31", "m -1fr", "y 29.02",
+		"FREQ=DAILY", "FREQ=DAILY;INTERVAL=5", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+		"FREQ=MONTHLY", "FREQ=MONTHLY;INTERVAL=5", "FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "FREQ=YEARLY", "FREQ=YEARLY;INTERVAL=3",
+		"FREQ=DAILY;UNTIL=20240201", "d 3; m 31",
+	}
+	starts := []string{"19990131", "19960229", "20000103", "20231031"}
+
+	for _, repeat := range rules {
+		for _, value := range starts {
+			start := mustDate(t, value)
+			rule, err := ParseRule(repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule, err = AnchorRule(rule, start); err != nil {
+				continue
+			}
+
+			want, err := NextAfter(stepOnly{rule}, start, now)
+			if err != nil {
+				t.Fatalf("%s from %s stepwise: %v", repeat, value, err)
+			}
+			got, err := NextAfter(rule, start, now)
+			if err != nil {
+				t.Fatalf("%s from %s: %v", repeat, value, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("%s from %s = %s, want %s", repeat, value, got.Format(DateFormat), want.Format(DateFormat))
+			}
+		}
+	}
+}
+
+func TestSkipFarPast(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		repeat string
+		want   string
+	}{
+		{"d 1", "20261018"},
+		{"FREQ=DAILY", "20261018"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "20261019"},
+		{"FREQ=MONTHLY", "20261101"},
+		{"FREQ=YEARLY", "20270101"},
+		{"d 3; w 6", "20261020"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			got, err := NextDate(now, "17000101", tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NextDate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	maxOccurrenceSteps = 100000
+)
+
+// ErrSearchLimit is returned when a rule needs too many steps to reach the
+// requested date.
+var ErrSearchLimit = errors.New("repeat search limit exceeded")
+
+// occurrenceIter walks the occurrences of a rule starting with the start date
+// itself and stops at the rule's UNTIL or COUNT bound, or after
+// maxOccurrenceSteps.
+type occurrenceIter struct {
+	rule    Rule
+	date    time.Time
+	n       int
+	until   time.Time
+	count   int
+	steps   int
+	limited bool
+}
+
+func newOccurrenceIter(rule Rule, start time.Time) *occurrenceIter {
+	it := &occurrenceIter{rule: rule, date: start, n: 1}
+	if bounded, ok := rule.(Bounded); ok {
+		it.until, it.count = bounded.Bounds()
+	}
+	return it
+}
+
+// skipTo jumps close to now when the rule allows it. Rules with a COUNT are
+// always walked, since every occurrence has to be counted.
+func (it *occurrenceIter) skipTo(now time.Time) {
+	if it.count > 0 || now.Before(it.date) {
+		return
+	}
+	if date, ok := skipRule(it.rule, it.date, now); ok {
+		it.date = date
+	}
+}
+
+func (it *occurrenceIter) next() bool {
+	if it.steps >= maxOccurrenceSteps {
+		it.limited = true
+		return false
+	}
+	it.steps++
+
+	next := it.rule.Next(it.date)
+	if next.IsZero() || (it.count > 0 && it.n >= it.count) || afterUntil(next, it.until) {
+		return false
+	}
+	it.date = next
+	it.n++
+	return true
+}
+
+// afterUntil reports whether date is past until. An until without a time of
+// day covers that whole day.
+func afterUntil(date, until time.Time) bool {
+	if until.IsZero() {
+		return false
+	}
+	if hour, min, sec := until.Clock(); hour == 0 && min == 0 && sec == 0 {
+		return date.Format(DateFormat) > until.Format(DateFormat)
+	}
+	return date.After(until)
+}
+
+// Occurrences parses repeat and returns at most max of its occurrences
+// between from and to for a series that began at start.
+func Occurrences(start time.Time, repeat string, from, to time.Time, max int) ([]time.Time, error) {
+	if to.Before(from) {
+		return nil, errors.New("end of the range is before its start")
+	}
+	if max < 1 {
+		return nil, errors.New("max must be positive")
+	}
+
+	rule, err := ParseRule(repeat)
+	if err != nil {
+		return nil, err
+	}
+	if rule, err = AnchorRule(rule, start); err != nil {
+		return nil, err
+	}
+
+	return OccurrencesOf(rule, start, from, to, max), nil
+}
+
+// OccurrencesOf returns at most max occurrences of rule between from and to
+// for a series that began at start, which counts as its first occurrence.
+func OccurrencesOf(rule Rule, start, from, to time.Time, max int) []time.Time {
+	dates := []time.Time{}
+	it := newOccurrenceIter(rule, start)
+	if from.After(start) {
+		it.skipTo(from.Add(-time.Nanosecond))
+	}
+	for ok := true; ok && !it.date.After(to) && len(dates) < max; ok = it.next() {
+		if !it.date.Before(from) {
+			dates = append(dates, it.date)
+		}
+	}
+
+	return dates
+}
+
+// FirstOccurrence returns the first occurrence of rule on or after start, or
+// the zero time. Rules that count from the start date, such as "d 7", always
+// occur on it, while calendar rules such as "w 1" only do when it fits them.
+func FirstOccurrence(rule Rule, start time.Time) time.Time {
+	if occursOn(rule, start) {
+		return start
+	}
+	return rule.Next(start)
+}
+
+// occursOn reports whether a series of rule starting at date has date as an
+// occurrence. Intervals are counted from the start, so they do not matter.
+func occursOn(rule Rule, date time.Time) bool {
+	switch r := rule.(type) {
+	case Daily, Relative, Review:
+		return true
+	case Intraday:
+		return !r.Window || fitsNext(r, date)
+	case Weekly:
+		r.Interval = 1
+		return fitsNext(r, date)
+	case Yearly:
+		r.Interval = 1
+		return len(r.Dates) == 0 || fitsNext(r, date)
+	case RRule:
+		r.Interval, r.Count, r.Until = 1, 0, time.Time{}
+		return len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 || fitsNext(r, date)
+	case Union:
+		for _, rule := range r.Rules {
+			if occursOn(rule, date) {
+				return true
+			}
+		}
+		return false
+	case BusinessDay:
+		return r.IsBusinessDay(date) && occursOn(r.Rule, date)
+	case Clocked:
+		return occursOn(r.Rule, date)
+	case Limited:
+		return occursOn(r.Rule, date)
+	case Excluding:
+		return !r.Dates[date.Format(DateFormat)] && occursOn(r.Rule, date)
+	}
+	return fitsNext(rule, date)
+}
+
+// fitsNext reports whether stepping rule from the day before date reaches
+// date exactly.
+func fitsNext(rule Rule, date time.Time) bool {
+	next := rule.Next(date.AddDate(0, 0, -1))
+	for i := 0; i < maxOccurrenceSteps && !next.IsZero() && next.Before(date); i++ {
+		next = rule.Next(next)
+	}
+	return next.Equal(date)
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFirstOccurrence(t *testing.T) {
+	tests := []struct {
+		repeat string
+		start  string
+		want   string
+	}{
+		{"d 7", "20240103", "20240103"},
+		{"y", "20240229", "20240229"},
+		{"w 1", "20240101", "20240101"},
+		{"w 5", "20240101", "20240105"},
+		{"w 1 2", "20240101", "20240101"},
+		{"w 1 2", "20240102", "20240115"},
+		{"m 15", "20240101", "20240115"},
+		{"m -1", "20240131", "20240131"},
+		{"y 2 01.01", "20240101", "20240101"},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1", "20240101", "20240101"},
+		{"FREQ=WEEKLY;INTERVAL=2", "20240103", "20240103"},
+		{"w 6,7 bd", "20240106", ""},
+		{"d 1 next-bd", "20240106", "20240108"},
+		{"w 1 next-bd", "20240102", "20240108"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat+" "+tt.start, func(t *testing.T) {
+			start := mustDate(t, tt.start)
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule, err = AnchorRule(rule, start); err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			if first := FirstOccurrence(rule, start); !first.IsZero() {
+				got = first.Format(DateFormat)
+			}
+			if got != tt.want {
+				t.Errorf("FirstOccurrence = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstOccurrenceTimed(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		repeat string
+		want   string
+	}{
+		{"h 2", "20240101T1030"},
+		{"h 2 09:00-18:00", "20240101T1100"},
+		{"c 0 9 * * *", "20240102T0900"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule = WithClock(rule, start.Hour(), start.Minute())
+
+			if got := FirstOccurrence(rule, start).Format(DateTimeFormat); got != tt.want {
+				t.Errorf("FirstOccurrence = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		repeat   string
+		start    string
+		from, to string
+		max      int
+		want     []string
+	}{
+		{"d 7", "20240101", "20240101", "20240131", 10,
+			[]string{"20240101", "20240108", "20240115", "20240122", "20240129"}},
+		{"d 7", "20240101", "20240110", "20240131", 2,
+			[]string{"20240115", "20240122"}},
+		{"m 15,-1", "20240115", "20240201", "20240331", 10,
+			[]string{"20240215", "20240229", "20240315", "20240331"}},
+		{"d 3; w 1", "20240103", "20240105", "20240115", 10,
+			[]string{"20240106", "20240108", "20240109", "20240112", "20240115"}},
+		{"y leap", "20240229", "20240101", "20321231", 10,
+			[]string{"20240229", "20280229", "20320229"}},
+		{"FREQ=DAILY;COUNT=3", "20240101", "20240102", "20240131", 10,
+			[]string{"20240102", "20240103"}},
+		{"w 1", "20240101", "20240102", "20240107", 10, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			got, err := Occurrences(mustDate(t, tt.start), tt.repeat, mustDate(t, tt.from), mustDate(t, tt.to), tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d", len(got), len(tt.want))
+			}
+			for i, date := range got {
+				if date.Format(DateFormat) != tt.want[i] {
+					t.Errorf("occurrence %d = %s, want %s", i, date.Format(DateFormat), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesErrors(t *testing.T) {
+	start := mustDate(t, "20240101")
+	tests := []struct {
+		name     string
+		repeat   string
+		from, to string
+		max      int
+	}{
+		{"reversed range", "d 1", "20240201", "20240101", 10},
+		{"zero max", "d 1", "20240101", "20240201", 0},
+		{"bad repeat", "d 0", "20240101", "20240201", 10},
+		{"leap policy off February 29", "y leap", "20240101", "20240201", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Occurrences(start, tt.repeat, mustDate(t, tt.from), mustDate(t, tt.to), tt.max); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relative repeats a fixed period after the task was completed rather than
+// after its scheduled date, e.g. d+ 7 for "a week after I last did it".
+// NextAfter computes it from now, the time of completion.
+type Relative struct {
+	Unit string
+	N    int
+}
+
+func parseRelative(parts []string) (Rule, error) {
+	kind := parts[0]
+	if err := checkParts(parts, 2, 2, "invalid repeat format for "+kind); err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n < 1 || n > maxInterval {
+		return nil, newRuleError(kind, CodeInterval, parts[1], len(kind)+1, "invalid interval: "+parts[1])
+	}
+
+	return Relative{Unit: strings.TrimSuffix(kind, "+"), N: n}, nil
+}
+
+func (r Relative) Next(after time.Time) time.Time {
+	switch r.Unit {
+	case "w":
+		return after.AddDate(0, 0, 7*r.N)
+	case "m":
+		return addMonthsClamped(after, r.N)
+	case "y":
+		return addMonthsClamped(after, 12*r.N)
+	default:
+		return after.AddDate(0, 0, r.N)
+	}
+}
+
+func (r Relative) String() string {
+	return r.Unit + "+ " + strconv.Itoa(r.N)
+}
+
+// addMonthsClamped adds months to date keeping it within the target month,
+// so January 31 plus one month is the last day of February.
+func addMonthsClamped(date time.Time, months int) time.Time {
+	year, month, day := date.Date()
+	first := time.Date(year, month+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	if last := daysIn(first.Year(), first.Month(), first.Location()); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// IsRelative reports whether the next occurrence of rule counts from the
+// completion of the task.
+func IsRelative(rule Rule) bool {
+	switch r := rule.(type) {
+	case Relative, Review:
+		return true
+	case BusinessDay:
+		return IsRelative(r.Rule)
+	case Clocked:
+		return IsRelative(r.Rule)
+	case Limited:
+		return IsRelative(r.Rule)
+	case Excluding:
+		return IsRelative(r.Rule)
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	DefaultEase = 2.5
+	MaxGrade    = 5
+
+	minEase           = 1.3
+	passGrade         = 3
+	maxReviewInterval = 3650
+)
+
+// Review is a spaced-repetition schedule in the style of SM-2, written as sr.
+// The repeat itself carries no numbers: the ease factor, the interval in days
+// and the number of successful reviews in a row belong to the task and change
+// with every recall grade. Like Relative, the next review counts from the
+// completion of the task.
+type Review struct {
+	Ease     float64
+	Interval int
+	Reps     int
+}
+
+func parseReview(parts []string) (Rule, error) {
+	if err := checkParts(parts, 1, 1, "invalid repeat format for sr"); err != nil {
+		return nil, err
+	}
+	return Review{}, nil
+}
+
+func (r Review) ease() float64 {
+	if r.Ease == 0 {
+		return DefaultEase
+	}
+	return r.Ease
+}
+
+// Grade returns the schedule after a review graded from 0 (forgotten) to
+// MaxGrade (perfect recall). A grade below 3 starts the card over.
+func (r Review) Grade(grade int) Review {
+	ease := r.ease()
+	next := Review{Reps: r.Reps + 1}
+	switch {
+	case grade < passGrade:
+		next.Reps, next.Interval = 0, 1
+	case r.Reps == 0:
+		next.Interval = 1
+	case r.Reps == 1:
+		next.Interval = 6
+	default:
+		next.Interval = int(math.Round(float64(r.Interval) * ease))
+	}
+	if next.Interval > maxReviewInterval {
+		next.Interval = maxReviewInterval
+	}
+
+	miss := float64(MaxGrade - grade)
+	ease += 0.1 - miss*(0.08+miss*0.02)
+	next.Ease = math.Max(minEase, math.Round(ease*100)/100)
+	return next
+}
+
+func (r Review) Next(after time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	return after.AddDate(0, 0, interval)
+}
+
+func (r Review) String() string {
+	return "sr"
+}
+
+// WithReview gives the spaced-repetition rule in rule the schedule of a task.
+func WithReview(rule Rule, review Review) Rule {
+	switch r := rule.(type) {
+	case Review:
+		return review
+	case BusinessDay:
+		r.Rule = WithReview(r.Rule, review)
+		return r
+	}
+	return rule
+}
+
+// ReviewOf returns the spaced-repetition schedule under the modifiers of
+// rule, if any.
+func ReviewOf(rule Rule) (Review, bool) {
+	switch r := rule.(type) {
+	case Review:
+		return r, true
+	case BusinessDay:
+		return ReviewOf(r.Rule)
+	case Clocked:
+		return ReviewOf(r.Rule)
+	case Limited:
+		return ReviewOf(r.Rule)
+	case Excluding:
+		return ReviewOf(r.Rule)
+	}
+	return Review{}, false
+}
@@ -0,0 +1,531 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+const (
+	rruleUntilFormat    = "20060102T150405Z"
+	rruleLocalFormat    = "20060102T150405"
+	rruleSearchYears    = 400
+	maxMonthlyOrdinal   = 5
+	maxYearlyOrdinal    = 53
+	maxSetPos           = 366
+	maxRRuleCount       = 10000
+	rrulePrefix         = "RRULE:"
+	rruleWeekdayLetters = 2
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry: a weekday with an optional ordinal, e.g. 2TU
+// for the second Tuesday or -1FR for the last Friday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule is a subset of an RFC 5545 recurrence rule. BySetPos picks
+// occurrences by their position among those of each period, e.g. -1 for the
+// last one.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	Count      int
+	Until      time.Time
+
+	// floating marks an UNTIL without Z, a local time that AnchorRule moves
+	// to the location of the start.
+	floating bool
+}
+
+func parseRRule(repeat string) (Rule, error) {
+	trimmed := strings.TrimLeft(repeat, " \t")
+	value := strings.ToUpper(strings.TrimRight(trimmed, " \t"))
+	base := len(repeat) - len(trimmed)
+	if strings.HasPrefix(value, rrulePrefix) {
+		value = value[len(rrulePrefix):]
+		base += len(rrulePrefix)
+	}
+
+	rule := RRule{Interval: 1}
+	partAt := make(map[string]int)
+	parts := strings.Split(value, ";")
+	for i, offset := range partOffsets(parts, ";") {
+		part, offset := parts[i], base+offset
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, newRuleError(KindRRule, CodeRRulePart, part, offset, "invalid RRULE part: "+part)
+		}
+		if _, seen := partAt[key]; seen {
+			return nil, newRuleError(KindRRule, CodeDuplicate, key, offset, "duplicate RRULE part: "+key)
+		}
+		partAt[key] = offset
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq, err = parseFrequency(val)
+		case "INTERVAL":
+			rule.Interval, err = parseBoundedInt(val, 1, maxInterval)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, -31, 31)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(val, 1, 12)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseIntList(val, -maxSetPos, maxSetPos)
+		case "COUNT":
+			rule.Count, err = parseBoundedInt(val, 1, maxRRuleCount)
+		case "UNTIL":
+			rule.Until, rule.floating, err = parseUntil(val)
+		default:
+			return nil, newRuleError(KindRRule, CodeUnsupported, key, offset, "invalid "+key+": unsupported RRULE part: "+key)
+		}
+		if err != nil {
+			ruleErr := shiftRuleError(err, offset+len(key)+1).(*RuleError)
+			ruleErr.Message = "invalid " + key + ": " + ruleErr.Message
+			return nil, ruleErr
+		}
+	}
+
+	if err := rule.validate(); err != nil {
+		ruleErr := err.(*RuleError)
+		if offset, ok := partAt[ruleErr.Token]; ok {
+			ruleErr.Offset = offset
+		} else {
+			ruleErr.Offset = len(repeat)
+		}
+		return nil, ruleErr
+	}
+
+	return rule, nil
+}
+
+// validate checks the combination of parts. Its errors name the offending
+// part in Token, without an offset.
+func (r RRule) validate() error {
+	if r.Freq == "" {
+		return newRuleError(KindRRule, CodeFormat, "", 0, "RRULE requires FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return newRuleError(KindRRule, CodeConflict, "UNTIL", 0, "RRULE must not contain both COUNT and UNTIL")
+	}
+
+	for _, wd := range r.ByDay {
+		if wd.N == 0 {
+			continue
+		}
+		switch {
+		case r.Freq == FreqMonthly || (r.Freq == FreqYearly && len(r.ByMonth) > 0):
+			if wd.N < -maxMonthlyOrdinal || wd.N > maxMonthlyOrdinal {
+				return newRuleError(KindRRule, CodeWeekday, "BYDAY", 0, fmt.Sprintf("invalid BYDAY ordinal: %d", wd.N))
+			}
+		case r.Freq == FreqYearly:
+			if wd.N < -maxYearlyOrdinal || wd.N > maxYearlyOrdinal {
+				return newRuleError(KindRRule, CodeWeekday, "BYDAY", 0, fmt.Sprintf("invalid BYDAY ordinal: %d", wd.N))
+			}
+		default:
+			return newRuleError(KindRRule, CodeConflict, "BYDAY", 0,
+				fmt.Sprintf("BYDAY ordinals are not allowed with FREQ=%s", r.Freq))
+		}
+	}
+
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return newRuleError(KindRRule, CodeConflict, "BYSETPOS", 0, "BYSETPOS requires BYDAY, BYMONTHDAY or BYMONTH")
+	}
+
+	if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return newRuleError(KindRRule, CodeConflict, "BYMONTHDAY", 0, "BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+
+	if len(r.ByMonthDay) > 0 && !daysFitMonths(r.ByMonthDay, r.ByMonth) {
+		return newRuleError(KindRRule, CodeImpossible, "BYMONTHDAY", 0,
+			"RRULE never occurs: none of BYMONTHDAY exists in BYMONTH")
+	}
+
+	return nil
+}
+
+func parseFrequency(value string) (Frequency, error) {
+	switch freq := Frequency(value); freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+		return freq, nil
+	default:
+		return "", newRuleError(KindRRule, CodeUnsupported, value, 0, "unsupported frequency "+value)
+	}
+}
+
+func parseBoundedInt(value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, newRuleError(KindRRule, CodeRange, value, 0, "out of range value "+value)
+	}
+	return n, nil
+}
+
+func parseIntList(value string, min, max int) ([]int, error) {
+	var result []int
+	items := strings.Split(value, ",")
+	for i, offset := range partOffsets(items, ",") {
+		n, err := strconv.Atoi(items[i])
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, newRuleError(KindRRule, CodeRange, items[i], offset, "out of range value "+items[i])
+		}
+		result = append(result, n)
+	}
+	return uniqueSorted(result, lessMonthDay), nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var result []WeekdayNum
+	items := strings.Split(value, ",")
+	for i, offset := range partOffsets(items, ",") {
+		part := items[i]
+		invalid := newRuleError(KindRRule, CodeWeekday, part, offset, "invalid weekday "+part)
+		if len(part) < rruleWeekdayLetters {
+			return nil, invalid
+		}
+
+		split := len(part) - rruleWeekdayLetters
+		day, ok := weekdayCodes[part[split:]]
+		if !ok {
+			return nil, invalid
+		}
+
+		var n int
+		if split > 0 {
+			var err error
+			n, err = strconv.Atoi(part[:split])
+			if err != nil || n == 0 {
+				return nil, invalid
+			}
+		}
+		result = append(result, WeekdayNum{N: n, Day: day})
+	}
+
+	return uniqueWeekdayNums(result), nil
+}
+
+func uniqueWeekdayNums(values []WeekdayNum) []WeekdayNum {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, b := isoWeekdayOf(values[i].Day), isoWeekdayOf(values[j].Day)
+		if a != b {
+			return a < b
+		}
+		return values[i].N < values[j].N
+	})
+
+	result := values[:1]
+	for _, wd := range values[1:] {
+		if wd != result[len(result)-1] {
+			result = append(result, wd)
+		}
+	}
+	return result
+}
+
+// parseUntil parses a date, a UTC time or a floating local time, which it
+// reports so that the time can be read in the start's location later.
+func parseUntil(value string) (time.Time, bool, error) {
+	for _, layout := range []string{DateFormat, rruleUntilFormat, rruleLocalFormat} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return until, layout == rruleLocalFormat, nil
+		}
+	}
+	return time.Time{}, false, newRuleError(KindRRule, CodeDate, value, 0, "invalid date "+value)
+}
+
+func (r RRule) Bounds() (time.Time, int) {
+	return r.Until, r.Count
+}
+
+func (r RRule) Next(after time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	first := r.periodStart(after)
+	periods := r.periodsPerYear() * rruleSearchYears
+	for k := 0; k <= periods; k++ {
+		start := r.shiftPeriods(first, k*interval)
+		end := r.shiftPeriods(start, 1)
+		if len(r.BySetPos) > 0 {
+			if next := r.nextInSet(start, end, after); !next.IsZero() {
+				return next
+			}
+			continue
+		}
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			if day.After(after) && r.matches(day, after) {
+				return day
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// nextInSet returns the first day after after that BySetPos picks from the
+// matching days of the period from start to end, or the zero time.
+func (r RRule) nextInSet(start, end, after time.Time) time.Time {
+	var set []time.Time
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if r.matches(day, after) {
+			set = append(set, day)
+		}
+	}
+
+	var next time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(set) + pos
+		}
+		if i < 0 || i >= len(set) || !set[i].After(after) {
+			continue
+		}
+		if next.IsZero() || set[i].Before(next) {
+			next = set[i]
+		}
+	}
+	return next
+}
+
+func (r RRule) periodStart(date time.Time) time.Time {
+	year, month, day := date.Date()
+	clock := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	}
+
+	switch r.Freq {
+	case FreqWeekly:
+		return clock(year, month, day-isoWeekday(date)+1)
+	case FreqMonthly:
+		return clock(year, month, 1)
+	case FreqYearly:
+		return clock(year, time.January, 1)
+	default:
+		return clock(year, month, day)
+	}
+}
+
+func (r RRule) shiftPeriods(date time.Time, n int) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		return date.AddDate(0, 0, 7*n)
+	case FreqMonthly:
+		return date.AddDate(0, n, 0)
+	case FreqYearly:
+		return date.AddDate(n, 0, 0)
+	default:
+		return date.AddDate(0, 0, n)
+	}
+}
+
+func (r RRule) periodsPerYear() int {
+	switch r.Freq {
+	case FreqWeekly:
+		return 53
+	case FreqMonthly:
+		return 12
+	case FreqYearly:
+		return 1
+	default:
+		return 366
+	}
+}
+
+// matches reports whether date is an occurrence. Parts missing from the rule
+// default to the matching part of anchor, the start date or a previous
+// occurrence, as RFC 5545 takes them from DTSTART.
+func (r RRule) matches(date, anchor time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(date.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(date) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesByDay(date) {
+		return false
+	}
+
+	switch r.Freq {
+	case FreqWeekly:
+		return len(r.ByDay) > 0 || date.Weekday() == anchor.Weekday()
+	case FreqMonthly:
+		return len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || date.Day() == anchor.Day()
+	case FreqYearly:
+		if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+			return true
+		}
+		return date.Day() == anchor.Day() && (len(r.ByMonth) > 0 || date.Month() == anchor.Month())
+	}
+	return true
+}
+
+func (r RRule) matchesMonthDay(date time.Time) bool {
+	day := date.Day()
+	lastDay := daysIn(date.Year(), date.Month(), date.Location())
+	for _, d := range r.ByMonthDay {
+		if d == day || (d < 0 && lastDay+d+1 == day) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RRule) matchesByDay(date time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day != date.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+
+		var pos, neg int
+		if r.Freq == FreqYearly && len(r.ByMonth) == 0 {
+			yearDay := date.YearDay()
+			daysInYear := time.Date(date.Year(), time.December, 31, 0, 0, 0, 0, date.Location()).YearDay()
+			pos, neg = (yearDay-1)/7+1, -((daysInYear-yearDay)/7 + 1)
+		} else {
+			pos, neg = monthWeekdayOrdinals(date)
+		}
+		if wd.N == pos || wd.N == neg {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		until := r.Until.Format(DateFormat)
+		if r.floating {
+			until = r.Until.Format(rruleLocalFormat)
+		} else if hour, min, sec := r.Until.Clock(); hour != 0 || min != 0 || sec != 0 {
+			until = r.Until.UTC().Format(rruleUntilFormat)
+		}
+		parts = append(parts, "UNTIL="+until)
+	}
+	return strings.Join(parts, ";")
+}
+
+func (wd WeekdayNum) String() string {
+	code := strings.ToUpper(wd.Day.String()[:rruleWeekdayLetters])
+	if wd.N == 0 {
+		return code
+	}
+	return strconv.Itoa(wd.N) + code
+}
+
+// ToRRule converts a rule starting at start to an equivalent RFC 5545 rule,
+// or fails when RRULE cannot express it exactly.
+func ToRRule(rule Rule, start time.Time) (RRule, error) {
+	switch r := rule.(type) {
+	case RRule:
+		return r, nil
+	case Daily:
+		return RRule{Freq: FreqDaily, Interval: r.Days}, nil
+	case Weekly:
+		byDay := make([]WeekdayNum, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			byDay[i] = WeekdayNum{Day: time.Weekday(day % 7)}
+		}
+		interval := r.Interval
+		if interval < 1 {
+			interval = 1
+		}
+		return RRule{Freq: FreqWeekly, Interval: interval, ByDay: byDay}, nil
+	case Monthly:
+		if len(r.Days) > 0 && len(r.Weekdays) > 0 {
+			return RRule{}, fmt.Errorf("repeat %q mixes month days and weekdays", rule)
+		}
+		return RRule{Freq: FreqMonthly, Interval: 1, ByMonthDay: r.Days, ByDay: r.Weekdays, ByMonth: r.Months}, nil
+	case Yearly:
+		return yearlyToRRule(r, start)
+	default:
+		return RRule{}, fmt.Errorf("repeat %q has no RRULE equivalent", rule)
+	}
+}
+
+// yearlyToRRule converts the dates of a yearly rule, which RRULE can express
+// only when they share the day or the month. Without dates the rule moves a
+// February 29 start to March 1 in common years, while RRULE would keep to
+// leap years.
+func yearlyToRRule(r Yearly, start time.Time) (RRule, error) {
+	rule := RRule{Freq: FreqYearly, Interval: r.interval()}
+	if r.Leap != "" {
+		return RRule{}, fmt.Errorf("repeat %q has no RRULE equivalent", r)
+	}
+	if len(r.Dates) == 0 && start.Month() == time.February && start.Day() == 29 {
+		return RRule{}, fmt.Errorf("repeat %q has no RRULE equivalent from February 29", r)
+	}
+
+	var days, months []int
+	for _, date := range r.Dates {
+		days = append(days, date.Day)
+		months = append(months, date.Month)
+	}
+	days, months = uniqueSorted(days, lessInt), uniqueSorted(months, lessInt)
+	if len(days) > 1 && len(months) > 1 {
+		return RRule{}, fmt.Errorf("repeat %q has no RRULE equivalent", r)
+	}
+
+	rule.ByMonthDay, rule.ByMonth = days, months
+	return rule, nil
+}
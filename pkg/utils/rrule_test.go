@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	date, err := time.Parse(DateFormat, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func TestToRRuleRoundTrip(t *testing.T) {
+	tests := []struct {
+		repeat string
+		start  string
+	}{
+		{"d 1", "20240101"},
+		{"d 7", "20240105"},
+		{"d 400", "20240229"},
+		{"w 1", "20240101"},
+		{"w 1,3,5", "20240103"},
+		{"w 7", "20240107"},
+		{"w 1,4 2", "20240101"},
+		{"w 3 3", "20240103"},
+		{"m 1", "20240101"},
+		{"m 15,-1", "20240115"},
+		{"m -2", "20240130"},
+		{"m 31", "20240131"},
+		{"m 1,15 2,8", "20240201"},
+		{"m 2tu", "20240109"},
+		{"m -1fr", "20240126"},
+		{"y", "20240315"},
+		{"y 2", "20240315"},
+		{"y 29.02", "20240229"},
+		{"y 01.01,01.07", "20240101"},
+		{"y 15.03,20.03", "20240315"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			start := mustDate(t, tt.start)
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule, err = AnchorRule(rule, start); err != nil {
+				t.Fatal(err)
+			}
+
+			rrule, err := ToRRule(rule, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := ParseRule(rrule.String())
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", rrule, err)
+			}
+
+			want, got := start, start
+			for i := 0; i < 30; i++ {
+				want, got = rule.Next(want), parsed.Next(got)
+				if !got.Equal(want) {
+					t.Fatalf("%s: occurrence %d = %s, want %s", rrule, i+1, got.Format(DateFormat), want.Format(DateFormat))
+				}
+			}
+		})
+	}
+}
+
+func TestToRRuleNoEquivalent(t *testing.T) {
+	tests := []struct {
+		repeat string
+		start  string
+	}{
+		{"y", "20240229"},
+		{"y 2", "20240229"},
+		{"y 29.02 feb28", "20240229"},
+		{"y 01.01,15.03", "20240101"},
+		{"m 1,2tu", "20240101"},
+		{"d 1 bd", "20240101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rrule, err := ToRRule(rule, mustDate(t, tt.start)); err == nil {
+				t.Errorf("ToRRule = %s, want an error", rrule)
+			}
+		})
+	}
+}
+
+func TestRRuleOccurrences(t *testing.T) {
+	tests := []struct {
+		repeat string
+		start  string
+		want   []string
+	}{
+		{"FREQ=MONTHLY;BYDAY=-1FR", "20240126",
+			[]string{"20240126", "20240223", "20240329", "20240426"}},
+		{"FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO", "20240527",
+			[]string{"20240527", "20250526", "20260525"}},
+		{"FREQ=YEARLY;BYDAY=-1SU", "20241229",
+			[]string{"20241229", "20251228", "20261227"}},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131",
+			[]string{"20240131", "20240229", "20240329", "20240430", "20240531", "20240628"}},
+		{"FREQ=MONTHLY;BYDAY=SA,SU;BYSETPOS=1,-1", "20240106",
+			[]string{"20240106", "20240128", "20240203", "20240225"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", "20240101",
+			[]string{"20240101", "20240104", "20240115", "20240118", "20240129"}},
+		{"FREQ=DAILY;INTERVAL=3;COUNT=3", "20240101",
+			[]string{"20240101", "20240104", "20240107"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20240118", "20240101",
+			[]string{"20240101", "20240104", "20240115", "20240118"}},
+		{"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=31;UNTIL=20241231T000000Z", "20240131",
+			[]string{"20240131", "20240331", "20240531", "20240731"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			start := mustDate(t, tt.start)
+			rule, err := ParseRule(tt.repeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A bounded rule must also stop after the last one.
+			max := len(tt.want)
+			if until, count := rule.(Bounded).Bounds(); !until.IsZero() || count > 0 {
+				max++
+			}
+			got := OccurrencesOf(rule, start, start, start.AddDate(5, 0, 0), max)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d", len(got), len(tt.want))
+			}
+			for i, date := range got {
+				if date.Format(DateFormat) != tt.want[i] {
+					t.Errorf("occurrence %d = %s, want %s", i, date.Format(DateFormat), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	tests := []struct {
+		repeat string
+		code   string
+	}{
+		{"FREQ=MONTHLY;BYSETPOS=1", CodeConflict},
+		{"FREQ=MONTHLY;BYDAY=MO;BYSETPOS=0", CodeRange},
+		{"FREQ=MONTHLY;BYDAY=MO;BYSETPOS=367", CodeRange},
+		{"FREQ=MONTHLY;BYDAY=-6MO", CodeWeekday},
+		{"FREQ=WEEKLY;BYDAY=-1MO", CodeConflict},
+		{"FREQ=YEARLY;BYDAY=-54MO", CodeWeekday},
+		{"FREQ=DAILY;INTERVAL=2;COUNT=3;UNTIL=20240101", CodeConflict},
+		{"FREQ=DAILY;INTERVAL=0", CodeRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			_, err := ParseRule(tt.repeat)
+			ruleErr, ok := err.(*RuleError)
+			if !ok {
+				t.Fatalf("err = %v, want a *RuleError", err)
+			}
+			if ruleErr.Code != tt.code {
+				t.Errorf("code = %s, want %s", ruleErr.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestFloatingUntil(t *testing.T) {
+	const repeat = "FREQ=DAILY;UNTIL=20241231T235959"
+	tests := []struct {
+		zone string
+		now  string
+		date string
+		want string
+	}{
+		// 23:59:59 UTC is already January 1 in Moscow and still December 31
+		// in New York; the rule ends at midnight local time in both.
+		{"Europe/Moscow", "20241230T1200", "20241230T0100", "20241231T0100"},
+		{"Europe/Moscow", "20241231T1200", "20241230T0100", ""},
+		{"America/New_York", "20241231T0000", "20241230T2200", "20241231T2200"},
+		{"America/New_York", "20241231T2300", "20241230T2200", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone+" "+tt.now, func(t *testing.T) {
+			loc := mustLocation(t, tt.zone)
+			now, err := time.ParseInLocation(DateTimeFormat, tt.now, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NextDate(now, tt.date, repeat)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoOccurrences) {
+					t.Errorf("NextDate(%s, %s) = %q, %v, want ErrNoOccurrences", tt.now, tt.date, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NextDate(%s, %s) = %s, want %s", tt.now, tt.date, got, tt.want)
+			}
+		})
+	}
+
+	rule, err := ParseRule(repeat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.String(); got != repeat {
+		t.Errorf("String() = %q, want %q", got, repeat)
+	}
+}
@@ -0,0 +1,446 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxInterval        = 400
+	monthlySearchYears = 400
+)
+
+// maxMonthDays is the longest possible length of each month.
+var maxMonthDays = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// Rule is a parsed repeat expression. Next returns the first occurrence
+// following after, where after is the start date or a previous occurrence,
+// or the zero time if the rule has no further occurrences.
+type Rule interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// Bounded is implemented by rules that stop after a date or after a number of
+// occurrences. The start date counts as the first occurrence.
+type Bounded interface {
+	Bounds() (until time.Time, count int)
+}
+
+type Daily struct {
+	Days int
+}
+
+type Weekly struct {
+	Weekdays []int
+	Interval int
+}
+
+type Monthly struct {
+	Days     []int
+	Weekdays []WeekdayNum
+	Months   []int
+}
+
+// ParseRule parses a repeat rule. Invalid rules are reported as *RuleError.
+func ParseRule(repeat string) (Rule, error) {
+	rule, err := parseRule(repeat)
+	if err != nil {
+		return nil, runeOffsets(err, repeat)
+	}
+	return rule, nil
+}
+
+func parseRule(repeat string) (Rule, error) {
+	if repeat == "" {
+		return nil, newRuleError("", CodeEmpty, "", 0, "repeat is empty")
+	}
+
+	if members, offsets := splitUnion(repeat); len(members) > 1 {
+		return parseUnion(members, offsets)
+	}
+
+	if rest, adjust, ok := parseAdjustment(repeat); ok {
+		rule, err := parseRule(rest)
+		if err != nil {
+			return nil, err
+		}
+		if _, nested := rule.(BusinessDay); nested {
+			return nil, newRuleError(KindBusiness, CodeDuplicate, string(adjust), len(rest)+1,
+				"duplicate business day modifier: "+string(adjust))
+		}
+		if _, intraday := rule.(Intraday); intraday && adjust != BusinessDaysOnly {
+			return nil, newRuleError(KindBusiness, CodeConflict, string(adjust), len(rest)+1,
+				"only bd applies to repeats within a day: "+string(adjust))
+		}
+		return BusinessDay{Rule: rule, Adjust: adjust}, nil
+	}
+
+	if strings.Contains(repeat, "=") {
+		return parseRRule(repeat)
+	}
+
+	parts := strings.Split(repeat, " ")
+	switch parts[0] {
+	case "d":
+		return parseDaily(parts)
+	case "w":
+		return parseWeekly(parts)
+	case "m":
+		return parseMonthly(parts)
+	case "c":
+		return parseCron(parts)
+	case "y":
+		return parseYearly(parts)
+	case "d+", "w+", "m+", "y+":
+		return parseRelative(parts)
+	case "h", "min":
+		return parseIntraday(parts)
+	case "sr":
+		return parseReview(parts)
+	default:
+		return nil, newRuleError("", CodeUnknownKind, parts[0], 0, "invalid repeat")
+	}
+}
+
+// checkParts reports a rule with too few or too many space-separated parts,
+// pointing at the first extra part or at the end of the rule.
+func checkParts(parts []string, min, max int, message string) error {
+	if len(parts) >= min && len(parts) <= max {
+		return nil
+	}
+
+	kind := parts[0]
+	offsets := partOffsets(parts, " ")
+	if len(parts) > max {
+		return newRuleError(kind, CodeFormat, parts[max], offsets[max], message)
+	}
+	end := offsets[len(parts)-1] + len(parts[len(parts)-1])
+	return newRuleError(kind, CodeFormat, "", end, message)
+}
+
+func parseDaily(parts []string) (Rule, error) {
+	if err := checkParts(parts, 2, 2, "invalid repeat format for d"); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	days, err := strconv.Atoi(parts[1])
+	if err != nil || days < 1 || days > maxInterval {
+		return nil, newRuleError("d", CodeInterval, parts[1], offsets[1], "invalid days: "+parts[1])
+	}
+
+	return Daily{Days: days}, nil
+}
+
+func parseWeekly(parts []string) (Rule, error) {
+	if err := checkParts(parts, 2, 3, "invalid repeat format for w"); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	var weekdays []int
+	items := strings.Split(parts[1], ",")
+	for i, offset := range partOffsets(items, ",") {
+		day, err := strconv.Atoi(items[i])
+		if err != nil || day < 1 || day > 7 {
+			return nil, newRuleError("w", CodeWeekday, items[i], offsets[1]+offset, "invalid weekday: "+items[i])
+		}
+		weekdays = append(weekdays, day)
+	}
+
+	interval := 1
+	if len(parts) == 3 {
+		var err error
+		interval, err = strconv.Atoi(parts[2])
+		if err != nil || interval < 1 || interval > maxInterval {
+			return nil, newRuleError("w", CodeInterval, parts[2], offsets[2], "invalid weeks interval: "+parts[2])
+		}
+	}
+
+	return Weekly{Weekdays: uniqueSorted(weekdays, lessInt), Interval: interval}, nil
+}
+
+func parseMonthly(parts []string) (Rule, error) {
+	if err := checkParts(parts, 2, 3, "invalid repeat format for m"); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	var days []int
+	var weekdays []WeekdayNum
+	items := strings.Split(parts[1], ",")
+	for i, offset := range partOffsets(items, ",") {
+		if wd, ok := parseMonthWeekday(items[i]); ok {
+			weekdays = append(weekdays, wd)
+			continue
+		}
+
+		day, err := strconv.Atoi(items[i])
+		if err != nil || day == 0 || day < -2 || day > 31 {
+			return nil, newRuleError("m", CodeDay, items[i], offsets[1]+offset, "invalid day: "+items[i])
+		}
+		days = append(days, day)
+	}
+
+	var months []int
+	if len(parts) == 3 {
+		items := strings.Split(parts[2], ",")
+		for i, offset := range partOffsets(items, ",") {
+			month, err := strconv.Atoi(items[i])
+			if err != nil || month < 1 || month > 12 {
+				return nil, newRuleError("m", CodeMonth, items[i], offsets[2]+offset, "invalid month: "+items[i])
+			}
+			months = append(months, month)
+		}
+	}
+
+	if len(weekdays) == 0 && !daysFitMonths(days, months) {
+		return nil, newRuleError("m", CodeImpossible, parts[1], offsets[1],
+			"repeat never occurs: none of the days exists in the given months")
+	}
+
+	return Monthly{
+		Days:     uniqueSorted(days, lessMonthDay),
+		Weekdays: uniqueWeekdayNums(weekdays),
+		Months:   uniqueSorted(months, lessInt),
+	}, nil
+}
+
+// parseMonthWeekday parses an nth weekday of the month such as 2tu (the second
+// Tuesday) or -1fr (the last Friday).
+func parseMonthWeekday(part string) (WeekdayNum, bool) {
+	if len(part) <= rruleWeekdayLetters {
+		return WeekdayNum{}, false
+	}
+
+	split := len(part) - rruleWeekdayLetters
+	day, ok := weekdayCodes[strings.ToUpper(part[split:])]
+	if !ok {
+		return WeekdayNum{}, false
+	}
+
+	n, err := strconv.Atoi(part[:split])
+	if err != nil || n == 0 || n < -maxMonthlyOrdinal || n > maxMonthlyOrdinal {
+		return WeekdayNum{}, false
+	}
+
+	return WeekdayNum{N: n, Day: day}, true
+}
+
+func (r Daily) Next(after time.Time) time.Time {
+	return after.AddDate(0, 0, r.Days)
+}
+
+func (r Daily) String() string {
+	return "d " + strconv.Itoa(r.Days)
+}
+
+// Next keeps the week of after active and then skips Interval-1 weeks, so the
+// week parity is anchored on the start date.
+func (r Weekly) Next(after time.Time) time.Time {
+	for day := after.AddDate(0, 0, 1); isoWeekday(day) != 1; day = day.AddDate(0, 0, 1) {
+		if containsInt(r.Weekdays, isoWeekday(day)) {
+			return day
+		}
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	weekStart := after.AddDate(0, 0, 7*interval+1-isoWeekday(after))
+	for i := 0; i < 7; i++ {
+		day := weekStart.AddDate(0, 0, i)
+		if containsInt(r.Weekdays, isoWeekday(day)) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+func (r Weekly) String() string {
+	s := "w " + joinInts(r.Weekdays)
+	if r.Interval > 1 {
+		s += " " + strconv.Itoa(r.Interval)
+	}
+	return s
+}
+
+// Next jumps from month to month and picks the earliest matching day of each,
+// giving up after monthlySearchYears.
+func (r Monthly) Next(after time.Time) time.Time {
+	year, month, day := after.Date()
+	for i := 0; i <= 12*monthlySearchYears; i++ {
+		first := time.Date(year, month+time.Month(i), 1, after.Hour(), after.Minute(), after.Second(), after.Nanosecond(), after.Location())
+		if len(r.Months) > 0 && !containsInt(r.Months, int(first.Month())) {
+			continue
+		}
+
+		minDay := 1
+		if i == 0 {
+			minDay = day + 1
+		}
+		if d := r.firstDay(first, minDay); d > 0 {
+			return first.AddDate(0, 0, d-1)
+		}
+	}
+	return time.Time{}
+}
+
+// firstDay returns the earliest matching day not before minDay in the month
+// starting at first, or 0 if there is none.
+func (r Monthly) firstDay(first time.Time, minDay int) int {
+	lastDay := daysIn(first.Year(), first.Month(), first.Location())
+	best := 0
+	consider := func(d int) {
+		if d >= minDay && d <= lastDay && (best == 0 || d < best) {
+			best = d
+		}
+	}
+
+	for _, d := range r.Days {
+		switch {
+		case d > 0:
+			consider(d)
+		case d < 0:
+			consider(lastDay + 1 + d)
+		}
+	}
+
+	firstWeekday := first.Weekday()
+	lastWeekday := (firstWeekday + time.Weekday(lastDay-1)) % 7
+	for _, wd := range r.Weekdays {
+		if wd.N > 0 {
+			consider(1 + int(wd.Day-firstWeekday+7)%7 + 7*(wd.N-1))
+		} else {
+			consider(lastDay - int(lastWeekday-wd.Day+7)%7 - 7*(-wd.N-1))
+		}
+	}
+	return best
+}
+
+func (r Monthly) matchesDay(date time.Time) bool {
+	day := date.Day()
+	lastDay := daysIn(date.Year(), date.Month(), date.Location())
+	for _, d := range r.Days {
+		if (d > 0 && d == day) || (d == -1 && day == lastDay) || (d == -2 && day == lastDay-1) {
+			return true
+		}
+	}
+
+	pos, neg := monthWeekdayOrdinals(date)
+	for _, wd := range r.Weekdays {
+		if wd.Day == date.Weekday() && (wd.N == pos || wd.N == neg) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Monthly) String() string {
+	days := make([]string, 0, len(r.Days)+len(r.Weekdays))
+	if len(r.Days) > 0 {
+		days = append(days, joinInts(r.Days))
+	}
+	for _, wd := range r.Weekdays {
+		days = append(days, strings.ToLower(wd.String()))
+	}
+
+	s := "m " + strings.Join(days, ",")
+	if len(r.Months) > 0 {
+		s += " " + joinInts(r.Months)
+	}
+	return s
+}
+
+// daysFitMonths reports whether at least one of the days of the month,
+// counted from the end when negative, exists in one of the months, or in any
+// month when months is empty.
+func daysFitMonths(days, months []int) bool {
+	if len(months) == 0 {
+		months = []int{1}
+	}
+	for _, d := range days {
+		if d < 0 {
+			d = -d
+		}
+		for _, m := range months {
+			if d <= maxMonthDays[m] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isoWeekday(date time.Time) int {
+	return isoWeekdayOf(date.Weekday())
+}
+
+func isoWeekdayOf(day time.Weekday) int {
+	if day == time.Sunday {
+		return 7
+	}
+	return int(day)
+}
+
+// monthWeekdayOrdinals returns the position of the date's weekday within its
+// month counted from the start (1 for the first) and from the end (-1 for the
+// last).
+func monthWeekdayOrdinals(date time.Time) (int, int) {
+	lastDay := daysIn(date.Year(), date.Month(), date.Location())
+	return (date.Day()-1)/7 + 1, -((lastDay-date.Day())/7 + 1)
+}
+
+func daysIn(year int, month time.Month, loc *time.Location) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+}
+
+func lessInt(a, b int) bool {
+	return a < b
+}
+
+// lessMonthDay orders day numbers as they appear in a month: positive days
+// first, then -2 (the day before last) and -1 (the last day).
+func lessMonthDay(a, b int) bool {
+	if (a > 0) != (b > 0) {
+		return a > 0
+	}
+	return a < b
+}
+
+func uniqueSorted[T comparable](values []T, less func(a, b T) bool) []T {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Slice(values, func(i, j int) bool { return less(values[i], values[j]) })
+
+	result := values[:1]
+	for _, v := range values[1:] {
+		if v != result[len(result)-1] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
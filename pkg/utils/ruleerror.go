@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codes of RuleError, stable for API clients.
+const (
+	CodeEmpty       = "empty"
+	CodeUnknownKind = "unknown_kind"
+	CodeFormat      = "invalid_format"
+	CodeInterval    = "invalid_interval"
+	CodeWeekday     = "invalid_weekday"
+	CodeDay         = "invalid_day"
+	CodeMonth       = "invalid_month"
+	CodeDate        = "invalid_date"
+	CodeRange       = "out_of_range"
+	CodeCronField   = "invalid_cron_field"
+	CodeRRulePart   = "invalid_rrule_part"
+	CodeDuplicate   = "duplicate"
+	CodeUnsupported = "unsupported"
+	CodeConflict    = "conflict"
+	CodeImpossible  = "impossible"
+)
+
+// Kinds of RuleError besides the rule letters d, w, m, y and c.
+const (
+	KindRRule    = "rrule"
+	KindBusiness = "bd"
+)
+
+// RuleError describes why a repeat rule was rejected. Token is the offending
+// part of the rule and Offset its position in characters, so that a client
+// can point at it; Token is empty when a part is missing, and Offset is then
+// where it was expected.
+type RuleError struct {
+	Kind    string
+	Code    string
+	Token   string
+	Offset  int
+	Message string
+}
+
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+// RuleErrors are the errors of several rules of a union, reported together.
+type RuleErrors []*RuleError
+
+func (e RuleErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e RuleErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+func newRuleError(kind, code, token string, offset int, message string) *RuleError {
+	return &RuleError{Kind: kind, Code: code, Token: token, Offset: offset, Message: message}
+}
+
+// shiftRuleError moves the offset of a RuleError found inside a part of the
+// rule that starts at base.
+func shiftRuleError(err error, base int) error {
+	var errs RuleErrors
+	if errors.As(err, &errs) {
+		for _, ruleErr := range errs {
+			ruleErr.Offset += base
+		}
+		return err
+	}
+
+	var ruleErr *RuleError
+	if errors.As(err, &ruleErr) {
+		ruleErr.Offset += base
+	}
+	return err
+}
+
+// partOffsets returns the byte offset of every part of s split by sep.
+func partOffsets(parts []string, sep string) []int {
+	offsets := make([]int, len(parts))
+	for i := 1; i < len(parts); i++ {
+		offsets[i] = offsets[i-1] + len(parts[i-1]) + len(sep)
+	}
+	return offsets
+}
+
+// runeOffsets converts the byte offset of a RuleError into characters of s.
+func runeOffsets(err error, s string) error {
+	var errs RuleErrors
+	if errors.As(err, &errs) {
+		for _, ruleErr := range errs {
+			runeOffsets(ruleErr, s)
+		}
+		return err
+	}
+
+	var ruleErr *RuleError
+	if errors.As(err, &ruleErr) && ruleErr.Offset > 0 && ruleErr.Offset <= len(s) {
+		ruleErr.Offset = utf8.RuneCountInString(s[:ruleErr.Offset])
+	}
+	return err
+}
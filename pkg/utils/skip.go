@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"time"
+)
+
+// skipper is implemented by rules whose occurrences follow a fixed pattern,
+// so that far-past start dates need not be walked one occurrence at a time.
+// skip returns a time not after now from which Next yields the same
+// occurrences as stepping from start does; start must not be after now.
+type skipper interface {
+	skip(start, now time.Time) (time.Time, bool)
+}
+
+func skipRule(rule Rule, start, now time.Time) (time.Time, bool) {
+	if s, ok := rule.(skipper); ok {
+		return s.skip(start, now)
+	}
+	return start, false
+}
+
+// civilDays counts calendar days from a to b, ignoring the time of day and
+// DST changes.
+func civilDays(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+// latestDay returns the last time not after now that has the clock of start,
+// but not earlier than start. It suits rules whose Next looks only at the
+// date of after.
+func latestDay(start, now time.Time) time.Time {
+	now = now.In(start.Location())
+	date := time.Date(now.Year(), now.Month(), now.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	if date.After(now) {
+		date = date.AddDate(0, 0, -1)
+	}
+	if date.Before(start) {
+		return start
+	}
+	return date
+}
+
+// skipPeriods moves start forward by whole periods of the given number of
+// days, keeping the result not after now.
+func skipPeriods(start, now time.Time, period int) time.Time {
+	k := civilDays(start, now) / period
+	date := start.AddDate(0, 0, k*period)
+	if date.After(now) && k > 0 {
+		date = start.AddDate(0, 0, (k-1)*period)
+	}
+	return date
+}
+
+func (r Daily) skip(start, now time.Time) (time.Time, bool) {
+	return skipPeriods(start, now, r.Days), true
+}
+
+// skip keeps the weekday of start, so the result stays in an active week.
+func (r Weekly) skip(start, now time.Time) (time.Time, bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	return skipPeriods(start, now, 7*interval), true
+}
+
+func (r Monthly) skip(start, now time.Time) (time.Time, bool) {
+	return latestDay(start, now), true
+}
+
+// skip moves start by whole intervals of years. A bare rule started on
+// February 29 takes one step first, as Next moves it off that day for good.
+func (r Yearly) skip(start, now time.Time) (time.Time, bool) {
+	if len(r.Dates) == 0 && start.Month() == time.February && start.Day() == 29 {
+		if r.Leap != "" {
+			return start, false
+		}
+		next := r.Next(start)
+		if next.After(now) {
+			return start, true
+		}
+		start = next
+	}
+
+	n := r.interval()
+	for k := (now.Year() - start.Year()) / n; k > 0; k-- {
+		if date := start.AddDate(k*n, 0, 0); !date.After(now) {
+			return date, true
+		}
+	}
+	return start, true
+}
+
+// skip moves start by whole intervals of the frequency, which only a COUNT
+// forbids. Months and years keep the day of start, so those that lack it are
+// passed over.
+func (r RRule) skip(start, now time.Time) (time.Time, bool) {
+	if r.Count > 0 {
+		return start, false
+	}
+
+	now = now.In(start.Location())
+	var periods int
+	switch r.Freq {
+	case FreqWeekly:
+		periods = civilDays(start, now) / 7
+	case FreqMonthly:
+		periods = (now.Year()-start.Year())*12 + int(now.Month()) - int(start.Month())
+	case FreqYearly:
+		periods = now.Year() - start.Year()
+	default:
+		periods = civilDays(start, now)
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	calendar := r.Freq == FreqMonthly || r.Freq == FreqYearly
+	for k := periods / interval; k > 0; k-- {
+		date := r.shiftPeriods(start, k*interval)
+		if !date.After(now) && (!calendar || date.Day() == start.Day()) {
+			return date, true
+		}
+	}
+	return start, true
+}
+
+func (r Cron) skip(start, now time.Time) (time.Time, bool) {
+	if r.Timed {
+		return now.In(start.Location()), true
+	}
+	return latestDay(start, now), true
+}
+
+// skip moves start by whole intervals; with a window Next depends only on
+// the time it is given, so now itself will do.
+func (r Intraday) skip(start, now time.Time) (time.Time, bool) {
+	if r.Window {
+		return now, true
+	}
+	step := r.step()
+	return start.Add(now.Sub(start) / step * step), true
+}
+
+// skip relies on Next walking from the anchor, which makes any time a valid
+// place to continue from.
+func (r BusinessDay) skip(start, now time.Time) (time.Time, bool) {
+	if !r.anchored() {
+		return start, false
+	}
+	return now, true
+}
+
+// skip relies on Next walking every rule from the anchor, like BusinessDay.
+func (r Union) skip(start, now time.Time) (time.Time, bool) {
+	if r.Anchor.IsZero() {
+		return start, false
+	}
+	return now, true
+}
+
+func (r Clocked) skip(start, now time.Time) (time.Time, bool) {
+	return skipRule(r.Rule, start, now)
+}
+
+func (r Excluding) skip(start, now time.Time) (time.Time, bool) {
+	return skipRule(r.Rule, start, now)
+}
+
+func (r Limited) skip(start, now time.Time) (time.Time, bool) {
+	return skipRule(r.Rule, start, now)
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const unionSeparator = "; "
+
+// Union repeats on the dates of any of its rules, e.g. "w 1; m 1" for every
+// Monday and the 1st of each month. Anchor is the start of the union, set by
+// AnchorRule: every rule is walked from there on its own, so that "d 3; w 1"
+// keeps both schedules. Without an anchor every rule counts from the latest
+// occurrence of the union, whichever rule produced it.
+type Union struct {
+	Rules  []Rule
+	Anchor time.Time
+}
+
+// splitUnion splits repeat at ';' and returns the rules with their byte
+// offsets. RRULE parts are separated by ';' too, so a KEY=VALUE segment
+// continues the RRULE before it unless that RRULE already has the key.
+func splitUnion(repeat string) ([]string, []int) {
+	segments := strings.Split(repeat, ";")
+
+	var members []string
+	var offsets []int
+	var keys map[string]bool
+	for i, offset := range partOffsets(segments, ";") {
+		segment := segments[i]
+		key, _, isPart := strings.Cut(strings.ToUpper(strings.TrimSpace(segment)), "=")
+		if isPart && keys != nil && !strings.HasPrefix(key, rrulePrefix) && !keys[key] {
+			members[len(members)-1] += ";" + segment
+			keys[key] = true
+			continue
+		}
+
+		members = append(members, segment)
+		offsets = append(offsets, offset)
+		keys = nil
+		if isPart {
+			keys = map[string]bool{strings.TrimPrefix(key, rrulePrefix): true}
+		}
+	}
+	return members, offsets
+}
+
+// parseUnion parses every rule of a union on its own and reports the errors
+// of all of them.
+func parseUnion(members []string, offsets []int) (Rule, error) {
+	var union Union
+	var errs RuleErrors
+	seen := make(map[string]bool)
+
+	for i, member := range members {
+		trimmed := strings.TrimLeft(member, " \t")
+		offset := offsets[i] + len(member) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, " \t")
+		if trimmed == "" {
+			errs = append(errs, newRuleError("", CodeEmpty, "", offset, "empty rule in union"))
+			continue
+		}
+
+		rule, err := parseRule(trimmed)
+		if err != nil {
+			var ruleErr *RuleError
+			if !errors.As(shiftRuleError(err, offset), &ruleErr) {
+				return nil, err
+			}
+			errs = append(errs, ruleErr)
+			continue
+		}
+
+		if err := checkUnionMember(rule, trimmed, offset, seen); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(union.Rules) > 0 && IsSubDaily(rule) != IsSubDaily(union.Rules[0]) {
+			errs = append(errs, newRuleError(ruleKind(trimmed), CodeConflict, trimmed, offset,
+				"repeats within a day cannot be combined with other rules"))
+			continue
+		}
+		union.Rules = append(union.Rules, rule)
+	}
+
+	switch len(errs) {
+	case 0:
+		return union, nil
+	case 1:
+		return nil, errs[0]
+	default:
+		return nil, errs
+	}
+}
+
+// ruleKind is the kind of RuleError for the rule written as text.
+func ruleKind(text string) string {
+	if strings.Contains(text, "=") {
+		return KindRRule
+	}
+	return strings.SplitN(text, " ", 2)[0]
+}
+
+func checkUnionMember(rule Rule, text string, offset int, seen map[string]bool) *RuleError {
+	kind := ruleKind(text)
+
+	if IsRelative(rule) {
+		return newRuleError(kind, CodeConflict, text, offset,
+			"repeats after completion cannot be combined with other rules")
+	}
+	if bounded, ok := rule.(Bounded); ok {
+		if _, count := bounded.Bounds(); count > 0 {
+			return newRuleError(kind, CodeUnsupported, text, offset, "COUNT is not supported in a union")
+		}
+	}
+	if seen[rule.String()] {
+		return newRuleError(kind, CodeDuplicate, text, offset, "duplicate rule in union: "+text)
+	}
+	seen[rule.String()] = true
+	return nil
+}
+
+func (r Union) Next(after time.Time) time.Time {
+	var next time.Time
+	for _, rule := range r.Rules {
+		date := r.nextOf(rule, after)
+		if date.IsZero() {
+			continue
+		}
+		if bounded, ok := rule.(Bounded); ok {
+			if until, _ := bounded.Bounds(); afterUntil(date, until) {
+				continue
+			}
+		}
+		if next.IsZero() || date.Before(next) {
+			next = date
+		}
+	}
+	return next
+}
+
+// nextOf returns the first occurrence of rule after after, walking it from
+// the anchor and jumping close to after when the rule allows it.
+func (r Union) nextOf(rule Rule, after time.Time) time.Time {
+	if r.Anchor.IsZero() || after.Before(r.Anchor) {
+		return rule.Next(after)
+	}
+
+	date, _ := skipRule(rule, r.Anchor, after)
+	for i := 0; i < maxOccurrenceSteps; i++ {
+		date = rule.Next(date)
+		if date.IsZero() || date.After(after) {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func (r Union) String() string {
+	texts := make([]string, len(r.Rules))
+	for i, rule := range r.Rules {
+		texts[i] = rule.String()
+	}
+	return strings.Join(texts, unionSeparator)
+}
+
+// mapRules applies fn to every rule of the union. Errors of the rules are
+// moved to their place in the canonical form of the union.
+func (r Union) mapRules(fn func(Rule) (Rule, error)) (Rule, error) {
+	rules := make([]Rule, len(r.Rules))
+	offset := 0
+	for i, rule := range r.Rules {
+		mapped, err := fn(rule)
+		if err != nil {
+			return nil, shiftRuleError(err, offset)
+		}
+		rules[i] = mapped
+		offset += len(rule.String()) + len(unionSeparator)
+	}
+	r.Rules = rules
+	return r, nil
+}
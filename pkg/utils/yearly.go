@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LeapPolicy tells where a February 29 date goes in years without one.
+type LeapPolicy string
+
+const (
+	LeapOnly  LeapPolicy = "leap"
+	LeapFeb28 LeapPolicy = "feb28"
+	LeapMar1  LeapPolicy = "mar1"
+)
+
+const (
+	yearlySearchYears = 400
+)
+
+// MonthDay is a date without a year, written DD.MM in repeat rules.
+type MonthDay struct {
+	Month int
+	Day   int
+}
+
+// Yearly repeats every Interval years. Without Dates the next occurrence is
+// the previous one moved by Interval years, so February 29 becomes March 1
+// unless Leap says otherwise. With Dates the rule occurs on each of them in
+// the year of the previous occurrence and then Interval years later.
+type Yearly struct {
+	Interval int
+	Dates    []MonthDay
+	Leap     LeapPolicy
+}
+
+func parseYearly(parts []string) (Rule, error) {
+	if err := checkParts(parts, 1, 4, "invalid repeat format for y"); err != nil {
+		return nil, err
+	}
+	offsets := partOffsets(parts, " ")
+
+	rule := Yearly{Interval: 1}
+	stage := 0
+	for i := 1; i < len(parts); i++ {
+		part, offset := parts[i], offsets[i]
+		switch {
+		case stage < 1 && part != "" && strings.Trim(part, "0123456789") == "":
+			interval, err := strconv.Atoi(part)
+			if err != nil || interval < 1 || interval > maxInterval {
+				return nil, newRuleError("y", CodeInterval, part, offset, "invalid years interval: "+part)
+			}
+			rule.Interval, stage = interval, 1
+		case stage < 2 && strings.Contains(part, "."):
+			dates, err := parseMonthDays(part)
+			if err != nil {
+				return nil, shiftRuleError(err, offset)
+			}
+			rule.Dates, stage = dates, 2
+		case stage < 3 && isLeapPolicy(part):
+			rule.Leap, stage = LeapPolicy(part), 3
+		default:
+			return nil, newRuleError("y", CodeFormat, part, offset, "invalid repeat format for y")
+		}
+	}
+
+	if rule.Leap != "" && len(rule.Dates) > 0 && !rule.hasLeapDay() {
+		return nil, newRuleError("y", CodeConflict, string(rule.Leap), offsets[len(parts)-1],
+			"leap-day policy requires 29.02 among the dates")
+	}
+	if rule.Leap == LeapOnly && len(rule.Dates) > 0 {
+		rule.Leap = ""
+	}
+
+	return rule, nil
+}
+
+func parseMonthDays(value string) ([]MonthDay, error) {
+	var dates []MonthDay
+	items := strings.Split(value, ",")
+	for i, offset := range partOffsets(items, ",") {
+		dayStr, monthStr, _ := strings.Cut(items[i], ".")
+		day, err1 := strconv.Atoi(dayStr)
+		month, err2 := strconv.Atoi(monthStr)
+		if err1 != nil || err2 != nil || month < 1 || month > 12 || day < 1 || day > maxMonthDays[month] {
+			return nil, newRuleError("y", CodeDay, items[i], offset, "invalid date: "+items[i])
+		}
+		dates = append(dates, MonthDay{Month: month, Day: day})
+	}
+
+	return uniqueSorted(dates, func(a, b MonthDay) bool {
+		return a.Month < b.Month || (a.Month == b.Month && a.Day < b.Day)
+	}), nil
+}
+
+func isLeapPolicy(value string) bool {
+	switch LeapPolicy(value) {
+	case LeapOnly, LeapFeb28, LeapMar1:
+		return true
+	}
+	return false
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func (r Yearly) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+func (r Yearly) hasLeapDay() bool {
+	for _, date := range r.Dates {
+		if date.Month == 2 && date.Day == 29 {
+			return true
+		}
+	}
+	return false
+}
+
+// leapDay returns the day that stands for February 29 in year under the
+// rule's policy, or false when the date is skipped that year.
+func (r Yearly) leapDay(year int) (MonthDay, bool) {
+	switch {
+	case isLeapYear(year):
+		return MonthDay{Month: 2, Day: 29}, true
+	case r.Leap == LeapFeb28:
+		return MonthDay{Month: 2, Day: 28}, true
+	case r.Leap == LeapMar1:
+		return MonthDay{Month: 3, Day: 1}, true
+	}
+	return MonthDay{}, false
+}
+
+func (r Yearly) Next(after time.Time) time.Time {
+	if len(r.Dates) == 0 {
+		return r.nextByYears(after)
+	}
+
+	year := after.Year()
+	for i := 0; i <= yearlySearchYears; i += r.interval() {
+		for _, date := range r.Dates {
+			if date.Month == 2 && date.Day == 29 {
+				var ok bool
+				if date, ok = r.leapDay(year + i); !ok {
+					continue
+				}
+			}
+			next := time.Date(year+i, time.Month(date.Month), date.Day, after.Hour(), after.Minute(), after.Second(), after.Nanosecond(), after.Location())
+			if civilDays(after, next) > 0 {
+				return next
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (r Yearly) nextByYears(after time.Time) time.Time {
+	if after.Month() != time.February || after.Day() != 29 || r.Leap == "" {
+		return after.AddDate(r.interval(), 0, 0)
+	}
+
+	for year := after.Year() + r.interval(); year <= after.Year()+yearlySearchYears; year += r.interval() {
+		if date, ok := r.leapDay(year); ok {
+			return time.Date(year, time.Month(date.Month), date.Day, after.Hour(), after.Minute(), after.Second(), after.Nanosecond(), after.Location())
+		}
+	}
+	return time.Time{}
+}
+
+func (r Yearly) String() string {
+	s := "y"
+	if r.Interval > 1 {
+		s += " " + strconv.Itoa(r.Interval)
+	}
+	if len(r.Dates) > 0 {
+		dates := make([]string, len(r.Dates))
+		for i, date := range r.Dates {
+			dates[i] = date.String()
+		}
+		s += " " + strings.Join(dates, ",")
+	}
+	if r.Leap != "" {
+		s += " " + string(r.Leap)
+	}
+	return s
+}
+
+func (d MonthDay) String() string {
+	return fmt.Sprintf("%02d.%02d", d.Day, d.Month)
+}
+
+// AnchorRule fixes a yearly rule with a leap-day policy but without dates to
+// the start date, which has to be February 29; otherwise the policy would be
+// lost after the first shifted occurrence. Business-day modifiers get start as
+// their anchor for the same reason, and unions so that each of their rules
+// keeps its own schedule. A floating UNTIL of an RRULE is read in the
+// location of start.
+func AnchorRule(rule Rule, start time.Time) (Rule, error) {
+	switch r := rule.(type) {
+	case Union:
+		r.Anchor = start
+		return r.mapRules(func(rule Rule) (Rule, error) {
+			return AnchorRule(rule, start)
+		})
+	case BusinessDay:
+		inner, err := AnchorRule(r.Rule, start)
+		if err != nil {
+			return nil, err
+		}
+		r.Rule = inner
+		r.Anchor = start
+		return r, nil
+	case RRule:
+		if r.floating {
+			year, month, day := r.Until.Date()
+			hour, min, sec := r.Until.Clock()
+			r.Until = time.Date(year, month, day, hour, min, sec, 0, start.Location())
+		}
+		return r, nil
+	case Yearly:
+		if r.Leap == "" || len(r.Dates) > 0 {
+			return r, nil
+		}
+		if start.Month() != time.February || start.Day() != 29 {
+			return nil, newRuleError("y", CodeConflict, string(r.Leap), len(r.String())-len(r.Leap),
+				"leap-day policy requires a start on February 29")
+		}
+		r.Dates = []MonthDay{{Month: 2, Day: 29}}
+		if r.Leap == LeapOnly {
+			r.Leap = ""
+		}
+		return r, nil
+	}
+	return rule, nil
+}
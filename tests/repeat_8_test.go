@@ -0,0 +1,888 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkNextDates(t *testing.T, now string, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=%s&date=%s&repeat=%s",
+			now, url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestWeeklyInterval(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "w 1 2", "20240129"},
+		{"20240108", "w 1 2", "20240205"},
+		{"20240101", "w 2,5 3", "20240213"},
+		{"20240124", "w 3,5 2", "20240207"},
+		{"20240101", "w 1 1", "20240129"},
+		{"20240101", "w 1 0", ""},
+		{"20240101", "w 1 401", ""},
+		{"20240101", "w 1 2 3", ""},
+	})
+}
+
+func TestMonthlyWeekday(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "m 2tu", "20240213"},
+		{"20240101", "m -1fr", "20240223"},
+		{"20240101", "m 4fr", "20240223"},
+		{"20240301", "m -1FR", "20240329"},
+		{"20240101", "m 5th", "20240229"},
+		{"20240101", "m 1mo 3,6", "20240304"},
+		{"20240101", "m 15,-1fr", "20240215"},
+		{"20240101", "m 6tu", ""},
+		{"20240101", "m 0tu", ""},
+		{"20240101", "m 2xx", ""},
+	})
+}
+
+func TestParseRepeat(t *testing.T) {
+	tbl := []struct {
+		text   string
+		repeat string
+	}{
+		{"каждый день", "d 1"},
+		{"через день", "d 2"},
+		{"по понедельникам и пятницам", "w 1,5"},
+		{"every 2 weeks on Monday", "w 1 2"},
+		{"every last day of month", "m -1"},
+		{"во второй вторник каждого месяца", "m 2tu"},
+		{"15 числа каждого месяца", "m 15"},
+		{"ежегодно", "y"},
+		{"every year on March 8", "m 8 3"},
+		{"every 2 months", "FREQ=MONTHLY;INTERVAL=2"},
+		{"каждый месяц", "m 1"},
+		{"monthly", "m 1"},
+		{"каждую вторую среду", "w 3 2"},
+		{"every second Wednesday", "w 3 2"},
+		{"каждую вторую среду месяца", "m 2we"},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/repeat/parse", map[string]any{
+			"text": v.text,
+			"date": "20240101",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Nil(t, ret["error"], v.text)
+		assert.Equal(t, v.repeat, ret["repeat"], v.text)
+		assert.NotEmpty(t, ret["text"], v.text)
+		assert.Len(t, ret["dates"], 5, v.text)
+	}
+
+	ret, err := postJSON("api/repeat/parse", map[string]any{"text": "каждый синий вторник"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "синий", ret["token"])
+	assert.Equal(t, float64(7), ret["offset"])
+
+	for _, v := range []struct {
+		text   string
+		offset float64
+	}{
+		{"every 0 days", 6},
+		{"каждые 0 дней", 7},
+	} {
+		ret, err = postJSON("api/repeat/parse", map[string]any{"text": v.text}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.text)
+		assert.Equal(t, "0", ret["token"], v.text)
+		assert.Equal(t, v.offset, ret["offset"], v.text)
+	}
+
+	ret, err = postJSON("api/repeat/parse", map[string]any{"text": "каждый месяц", "date": "20240131"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "m 31", ret["repeat"])
+	assert.Equal(t, []any{"20240131", "20240331", "20240531", "20240731", "20240831"}, ret["dates"])
+}
+
+func TestParseRepeatPreview(t *testing.T) {
+	tbl := []struct {
+		text  string
+		dates []any
+	}{
+		{"каждый день", []any{"20240101", "20240102", "20240103"}},
+		{"по пятницам", []any{"20240105", "20240112", "20240119"}},
+		{"every 2 weeks on Monday", []any{"20240101", "20240115", "20240129"}},
+		{"15 числа каждого месяца", []any{"20240115", "20240215", "20240315"}},
+		{"every last day of month", []any{"20240131", "20240229", "20240331"}},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/repeat/parse", map[string]any{
+			"text":  v.text,
+			"date":  "20240101",
+			"limit": 3,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Nil(t, ret["error"], v.text)
+		assert.Equal(t, v.dates, ret["dates"], v.text)
+	}
+}
+
+func TestValidateRepeat(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		code   string
+		token  string
+		offset float64
+	}{
+		{"w 1,8", "invalid_weekday", "8", 4},
+		{"m 1,2tu 13", "invalid_month", "13", 8},
+		{"d 1 2", "invalid_format", "2", 4},
+		{"q 1", "unknown_kind", "q", 0},
+		{"FREQ=WEEKLY;BYDAY=MO,XX", "invalid_weekday", "XX", 21},
+		{"c 0 25 * * *", "invalid_cron_field", "25", 4},
+		{"m 30,31 2", "impossible", "30,31", 2},
+		{"FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=31", "impossible", "BYMONTHDAY", 22},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/repeat/validate?repeat="+url.QueryEscape(v.repeat), nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.repeat)
+		assert.Equal(t, v.code, ret["code"], v.repeat)
+		assert.Equal(t, v.token, ret["token"], v.repeat)
+		assert.Equal(t, v.offset, ret["offset"], v.repeat)
+	}
+
+	ret, err := postJSON("api/repeat/validate?repeat="+url.QueryEscape("w 7,1"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["valid"])
+	assert.Equal(t, "w 1,7", ret["repeat"])
+}
+
+func TestFarPastStart(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"19000101", "d 1", "20240127"},
+		{"19000101", "d 7", "20240129"},
+		{"19000101", "w 1,3 2", "20240129"},
+		{"19000101", "m 15,-1", "20240131"},
+		{"19000101", "m 2tu", "20240213"},
+		{"19040229", "y", "20240301"},
+		{"17000101", "FREQ=DAILY", "20240127"},
+		{"17000101", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "20240129"},
+		{"19000131", "FREQ=MONTHLY", "20240131"},
+		{"20240101", "m 31 2", ""},
+		{"20240101", "m 30,31 2", ""},
+		{"20240101", "m 29 2", "20240229"},
+	})
+}
+
+func TestYearlyRules(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20200101", "y 2", "20260101"},
+		{"20200101", "y 1.3,1.9", "20240301"},
+		{"20210101", "y 2 1.3,1.9", "20250301"},
+		{"20210101", "y 3 29.02", "20240229"},
+		{"20200229", "y feb28", "20240229"},
+		{"20200229", "y mar1", "20240229"},
+		{"20200229", "y leap", "20240229"},
+		{"20230301", "y 29.02 feb28", "20240229"},
+		{"20240101", "y feb28", ""},
+		{"20240101", "y 1.3 feb28", ""},
+		{"20240101", "y 31.02", ""},
+		{"20240101", "y 1.3 2", ""},
+	})
+
+	checkNextDates(t, "20250101", []nextDate{
+		{"20240229", "y feb28", "20250228"},
+		{"20240229", "y mar1", "20250301"},
+		{"20240229", "y 29.02 feb28", "20250228"},
+	})
+}
+
+func TestYearlyLeapTask(t *testing.T) {
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   "20280229",
+		"title":  "Високосный день",
+		"repeat": "y feb28",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"repeat":"y feb28"`)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"20290228"`)
+	assert.Contains(t, string(body), `"repeat":"y feb28"`)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   "20280301",
+		"title":  "Не високосный день",
+		"repeat": "y feb28",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+
+	// The leap-only policy is stored as written too.
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   "20280229",
+		"title":  "Только в високосный год",
+		"repeat": "y leap",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"repeat":"y leap"`)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"20320229"`)
+	assert.Contains(t, string(body), `"repeat":"y leap"`)
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
+
+func TestCompletionRelative(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "d+ 7", "20240202"},
+		{"20240301", "d+ 7", "20240202"},
+		{"20240101", "w+ 2", "20240209"},
+		{"20231231", "m+ 1", "20240226"},
+		{"20240101", "y+ 1", "20250126"},
+		{"20240101", "d+ 0", ""},
+		{"20240101", "d+", ""},
+	})
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   now.AddDate(0, 0, 10).Format("20060102"),
+		"title":  "Полить цветы",
+		"repeat": "d+ 3",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	_, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"`+now.AddDate(0, 0, 3).Format("20060102")+`"`)
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
+
+func TestCatchUp(t *testing.T) {
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format("20060102")
+	}
+	taskDates := func(title string) []string {
+		dates := []string{}
+		for _, task := range getTasks(t, url.QueryEscape(title)) {
+			if task["title"] == title {
+				dates = append(dates, task["date"])
+			}
+		}
+		return dates
+	}
+
+	// A new task dated in the past starts at its next occurrence under every
+	// policy and has no missed tasks.
+	ids := make(map[string]string)
+	for _, policy := range []string{"skip", "one", "each"} {
+		title := "Подкормка " + policy
+		ret, err := postJSON("api/task", map[string]any{
+			"date":    day(-10),
+			"title":   title,
+			"repeat":  "d 3",
+			"catchup": policy,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		ids[policy] = fmt.Sprint(ret["id"])
+		assert.Equal(t, []string{day(2)}, taskDates(title), policy)
+	}
+
+	// Moving an existing task into the past catches up on it.
+	ret, err := postJSON("api/task", map[string]any{
+		"id":      ids["each"],
+		"date":    day(-10),
+		"title":   "Подкормка each",
+		"repeat":  "d 3",
+		"catchup": "each",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []string{day(-10), day(-7), day(-4), day(-1), day(2)}, taskDates("Подкормка each"))
+	for _, task := range getTasks(t, url.QueryEscape("Подкормка each")) {
+		if task["title"] == "Подкормка each" && task["id"] != ids["each"] {
+			assert.Empty(t, task["repeat"])
+			_, err = requestJSON("api/task?id="+task["id"], nil, http.MethodDelete)
+			assert.NoError(t, err)
+		}
+	}
+
+	oneID := ids["one"]
+	ret, err = postJSON("api/task", map[string]any{
+		"id":      oneID,
+		"date":    day(-10),
+		"title":   "Подкормка one",
+		"repeat":  "d 3",
+		"catchup": "one",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	body, err := requestJSON("api/task?id="+oneID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"`+day(-10)+`"`)
+	assert.Contains(t, string(body), `"catchup":"one"`)
+
+	_, err = postJSON("api/task/done?id="+oneID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	body, err = requestJSON("api/task?id="+oneID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"`+day(-7)+`"`)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":      oneID,
+		"date":    day(-7),
+		"title":   "Подкормка one",
+		"repeat":  "d 3",
+		"catchup": "skip",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	body, err = requestJSON("api/task?id="+oneID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"`+day(2)+`"`)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":   "Неизвестная политика",
+		"repeat":  "d 3",
+		"catchup": "all",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, id := range ids {
+		_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestIntraday(t *testing.T) {
+	checkNextDates(t, "20240126T1500", []nextDate{
+		{"20240126", "h 4", "20240126T1600"},
+		{"20240126T0930", "h 4", "20240126T1730"},
+		{"20200126T0100", "min 7", "20240126T1503"},
+		{"20240101", "min 45 09:00-18:00", "20240126T1545"},
+		{"20240126", "min 30 09:00-17:00", "20240126T1530"},
+		{"20240126", "h 1 09:00-15:00", "20240127T0900"},
+		{"20240126", "h 1 09:00-18:00 bd", "20240126T1600"},
+		{"20240126", "h 25", ""},
+		{"20240126", "min 0", ""},
+		{"20240126", "min 30 18:00-09:00", ""},
+		{"20240126", "h 1 next-bd", ""},
+	})
+	checkNextDates(t, "20240127T1200", []nextDate{
+		{"20240126", "min 30 09:00-18:00 bd", "20240129T0900"},
+	})
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("20060102")
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   tomorrow,
+		"title":  "Проверить мониторинг",
+		"repeat": "min 30 09:00-18:00",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"date":"`+tomorrow+`","title"`)
+	assert.Contains(t, string(body), `"time":"09:00"`)
+	assert.Contains(t, string(body), `"repeat_text":"каждые 30 минут с 09:00 до 18:00"`)
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
+
+func TestUnionRules(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "w 1; m 1", "20240129"},
+		{"20240130", "w 1;m 1", "20240201"},
+		{"20240101", "FREQ=WEEKLY;BYDAY=MO;FREQ=MONTHLY;BYMONTHDAY=1", "20240129"},
+		{"20240101", "m 1; FREQ=WEEKLY;BYDAY=WE", "20240131"},
+		{"20240101", "w 1; FREQ=DAILY;UNTIL=20240127", "20240127"},
+		{"20240101", "w 1;", ""},
+		{"20240101", "w 1; w 1", ""},
+		{"20240101", "w 1; d+ 3", ""},
+		{"20240101", "w 1; FREQ=DAILY;COUNT=3", ""},
+		{"19000101", "d 3; m 31", "20240129"},
+	})
+
+	// Every rule keeps its own schedule from the start date.
+	checkNextDates(t, "20240108", []nextDate{
+		{"20240103", "d 3; w 1", "20240109"},
+		{"20240103", "w 1; d 3", "20240109"},
+	})
+	checkNextDates(t, "20240120", []nextDate{
+		{"20240101", "d 5; m 10", "20240121"},
+		{"20240101", "d 5; m 23", "20240121"},
+		{"20240101", "d 7 bd; w 3", "20240122"},
+	})
+
+	body, err := requestJSON("api/repeat/validate?repeat="+url.QueryEscape("w 1;FREQ=WEEKLY;BYDAY=FR"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"repeat":"w 1; FREQ=WEEKLY;BYDAY=FR"`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":  "Два расписания",
+		"repeat": "d 0; w 1; m 32",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	errs, _ := ret["errors"].([]any)
+	if assert.Len(t, errs, 2) {
+		first, second := errs[0].(map[string]any), errs[1].(map[string]any)
+		assert.Equal(t, "d", first["kind"])
+		assert.Equal(t, float64(2), first["offset"])
+		assert.Equal(t, "m", second["kind"])
+		assert.Equal(t, "32", second["token"])
+		assert.Equal(t, float64(12), second["offset"])
+	}
+}
+
+func TestSpacedRepetition(t *testing.T) {
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"title":  "Карточка: неправильные глаголы",
+		"repeat": "sr",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	for _, step := range []struct {
+		grade    string
+		days     int
+		ease     string
+		interval string
+	}{
+		{"5", 1, "2.6", "1"},
+		{"5", 6, "2.7", "6"},
+		{"5", 16, "2.8", "16"},
+		{"2", 1, "2.48", "1"},
+	} {
+		ret, err = postJSON("api/task/done?id="+id+"&grade="+step.grade, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), `"date":"`+now.AddDate(0, 0, step.days).Format("20060102")+`"`)
+		assert.Contains(t, string(body), `"ease":`+step.ease+`,`)
+		assert.Contains(t, string(body), `"interval":"`+step.interval+`"`)
+	}
+
+	ret, err = postJSON("api/task/done?id="+id+"&grade=6", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":  "Карточка без оценки",
+		"repeat": "sr; w 1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
+
+func TestFullTextSearch(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	addTitled := func(title, comment string) string {
+		ret, err := postJSON("api/task", map[string]any{
+			"title":   title,
+			"comment": comment,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		return fmt.Sprint(ret["id"])
+	}
+	// found returns the tasks among ids that the search finds, in the order
+	// it returns them. Other tasks in the database are ignored.
+	found := func(search string, ids ...string) []string {
+		var got []string
+		for _, task := range getTasks(t, url.QueryEscape(search)) {
+			for _, id := range ids {
+				if task["id"] == id {
+					got = append(got, id)
+					assert.Contains(t, task["snippet"], "<mark>", search)
+				}
+			}
+		}
+		return got
+	}
+
+	film := addTitled("Посмотреть фильм", "Фильм выбрать заранее")
+	series := addTitled("Досмотреть сериал", "или фильм")
+
+	assert.Equal(t, []string{film, series}, found("ФИЛЬМ", film, series))
+	assert.Equal(t, []string{film, series}, found("фил*", film, series))
+	assert.Equal(t, []string{film}, found(`"посмотреть фильм"`, film, series))
+	assert.Equal(t, []string{film}, found("фильм -сериал", film, series))
+	assert.Equal(t, []string{series}, found("сериал OR сериалы", film, series))
+	assert.Empty(t, found(`"фильм посмотреть"`, film, series))
+
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape("-фильм"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	for _, id := range []string{film, series} {
+		_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":   "Позвонить в стоматологию",
+		"comment": "Записаться на приём",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	assert.Empty(t, getTasks(t, url.QueryEscape("стамотология")))
+	for _, search := range []string{"стамотология", "ПОЗВНИТЬ", "записатся приеём"} {
+		tasks := getTasks(t, url.QueryEscape(search)+"&fuzzy=1")
+		if assert.NotEmpty(t, tasks, search) {
+			assert.Equal(t, id, tasks[0]["id"], search)
+			assert.Contains(t, tasks[0]["snippet"], "<mark>", search)
+		}
+	}
+
+	_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, getTasks(t, url.QueryEscape("стамотология")+"&fuzzy=1"))
+}
+
+func TestExceptions(t *testing.T) {
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format("20060102")
+	}
+	exceptions := func(id string) string {
+		body, err := requestJSON("api/task/exceptions?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		return strings.TrimSpace(string(body))
+	}
+	taskDate := func(id string) string {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]any
+		assert.NoError(t, json.Unmarshal(body, &task))
+		return fmt.Sprint(task["date"])
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   day(1),
+		"title":  "Бассейн",
+		"repeat": "d 2",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, `{"dates":[]}`, exceptions(id))
+
+	for _, date := range []string{day(1), day(5)} {
+		ret, err = postJSON("api/task/exceptions?id="+id+"&date="+date, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+	}
+	ret, err = postJSON("api/task/exceptions?id="+id+"&date=2024-01-01", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, `{"dates":["`+day(1)+`","`+day(5)+`"]}`, exceptions(id))
+
+	// A past date moves to the first occurrence after today that is not an
+	// exception, and an excluded date moves to the next occurrence.
+	for _, v := range []struct{ date, want string }{
+		{day(-1), day(3)},
+		{day(5), day(7)},
+		{day(3), day(3)},
+	} {
+		ret, err = postJSON("api/task", map[string]any{
+			"id":     id,
+			"date":   v.date,
+			"title":  "Бассейн",
+			"repeat": "d 2",
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		assert.Equal(t, v.want, taskDate(id), v.date)
+	}
+
+	_, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, day(7), taskDate(id))
+
+	ret, err = postJSON("api/task/exceptions?id="+id+"&date="+day(1), nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	ret, err = postJSON("api/task/exceptions?id="+id+"&date="+day(1), nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Equal(t, `{"dates":["`+day(5)+`"]}`, exceptions(id))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":  day(1),
+		"title": "Разовая",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	onceID := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/exceptions?id="+onceID+"&date="+day(1), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	for _, id := range []string{id, onceID} {
+		_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	occurrences := func(query string) map[string]any {
+		body, err := requestJSON("api/occurrences?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var ret map[string]any
+		assert.NoError(t, json.Unmarshal(body, &ret), string(body))
+		return ret
+	}
+
+	tbl := []struct {
+		query string
+		dates []any
+	}{
+		{"date=20240101&repeat=w+2&from=20240101&to=20240131",
+			[]any{"20240102", "20240109", "20240116", "20240123", "20240130"}},
+		{"date=20240101&repeat=d+7&from=20240101&to=20240115",
+			[]any{"20240101", "20240108", "20240115"}},
+		{"date=20240101&repeat=m+-1&from=20240101&to=20240331&limit=2",
+			[]any{"20240131", "20240229"}},
+		{"date=20240101&repeat=w+1+2&from=20240110&to=20240131",
+			[]any{"20240115", "20240129"}},
+		{"date=20240101&repeat=d+1&from=20240101&to=20260101&limit=1000", nil},
+	}
+	for _, v := range tbl {
+		ret := occurrences(v.query)
+		assert.Nil(t, ret["error"], v.query)
+		if v.dates == nil {
+			assert.Len(t, ret["dates"], 732, v.query)
+			continue
+		}
+		assert.Equal(t, v.dates, ret["dates"], v.query)
+	}
+
+	for _, query := range []string{
+		"date=20240101&repeat=d+1&limit=0",
+		"date=20240101&repeat=d+1&limit=1001",
+		"date=20240101&repeat=d+1&limit=x",
+		"date=20240101&repeat=d+1&from=20240201&to=20240101",
+		"date=20240101",
+		"repeat=d+1",
+		"date=20240101&repeat=w+8",
+	} {
+		assert.NotEmpty(t, occurrences(query)["error"], query)
+	}
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format("20060102")
+	}
+	id := addTask(t, task{date: day(1), title: "Полив", repeat: "d 3"})
+	onceID := addTask(t, task{date: day(1), title: "Разовая"})
+
+	ret := occurrences("id=" + id + "&from=" + day(0) + "&to=" + day(10))
+	assert.Equal(t, []any{day(1), day(4), day(7), day(10)}, ret["dates"])
+	ret = occurrences("id=" + id + "&from=" + day(2) + "&to=" + day(10) + "&limit=1")
+	assert.Equal(t, []any{day(4)}, ret["dates"])
+	ret = occurrences("id=" + onceID + "&from=" + day(0) + "&to=" + day(10))
+	assert.Equal(t, []any{day(1)}, ret["dates"])
+	ret = occurrences("id=" + onceID + "&from=" + day(2) + "&to=" + day(10))
+	assert.Equal(t, []any{}, ret["dates"])
+	assert.NotEmpty(t, occurrences("id=x")["error"])
+
+	for _, id := range []string{id, onceID} {
+		_, err := requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCron(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "c 0 9 * * 1-5", "20240126T0900"},
+		{"20240101", "c 0 9 * * sat,sun", "20240127T0900"},
+		{"20240101", "c 0 9 1 * *", "20240201T0900"},
+		{"20240101", "c 0 9 1-10/3 * *", "20240201T0900"},
+		{"20240101", "c 0 9 13 * 1", "20240129T0900"},
+		{"20240101", "c 0 9 */2 * mon", "20240129T0900"},
+		{"20240101", "c 0 9 * feb mon", "20240205T0900"},
+		{"20240101", "c */30 * * * *", "20240126T0030"},
+		{"20240101T1000", "c 30 8,18 * * *", "20240126T0830"},
+		{"20240101", "c 0 9 * * 8", ""},
+		{"20240101", "c 0 9 31 2 *", ""},
+		{"20240101", "c 0 9 * *", ""},
+	})
+
+	taskTime := func(id string) (string, string) {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]any
+		assert.NoError(t, json.Unmarshal(body, &task))
+		return fmt.Sprint(task["date"]), fmt.Sprint(task["time"])
+	}
+
+	// A task due tomorrow stays there whatever the time of day now.
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	firstOfMonth := time.Date(tomorrow.Year(), tomorrow.Month()+1, 1, 0, 0, 0, 0, tomorrow.Location())
+	for _, v := range []struct {
+		repeat string
+		dates  []string
+		time   string
+	}{
+		{"c 0 9 * * *", []string{tomorrow.AddDate(0, 0, 1).Format("20060102"), tomorrow.AddDate(0, 0, 2).Format("20060102")}, "09:00"},
+		{"c 0 18 1 * *", []string{firstOfMonth.Format("20060102"), firstOfMonth.AddDate(0, 1, 0).Format("20060102")}, "18:00"},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":   tomorrow.Format("20060102"),
+			"title":  "Бэкап",
+			"repeat": v.repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		date, clock := taskTime(id)
+		assert.Equal(t, tomorrow.Format("20060102"), date, v.repeat)
+		assert.Equal(t, v.time, clock, v.repeat)
+
+		for _, want := range v.dates {
+			ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Empty(t, ret)
+			date, clock = taskTime(id)
+			assert.Equal(t, want, date, v.repeat)
+			assert.Equal(t, v.time, clock, v.repeat)
+		}
+
+		_, err = requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestEndConditions(t *testing.T) {
+	checkNextDates(t, "20240126", []nextDate{
+		{"20240101", "FREQ=DAILY;UNTIL=20240127", "20240127"},
+		{"20240101", "FREQ=DAILY;UNTIL=20240126", ""},
+		{"20240101", "FREQ=DAILY;UNTIL=20240127T000000Z", "20240127"},
+		{"20240101", "FREQ=DAILY;INTERVAL=2;UNTIL=20240127", "20240127"},
+		{"20240101", "FREQ=DAILY;INTERVAL=2;UNTIL=20240126", ""},
+		{"20240101", "FREQ=DAILY;COUNT=27", "20240127"},
+		{"20240101", "FREQ=DAILY;COUNT=26", ""},
+		{"20240101", "FREQ=WEEKLY;COUNT=5", "20240129"},
+		{"20240101", "FREQ=WEEKLY;COUNT=4", ""},
+		{"20240131", "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=2", "20240331"},
+		{"20240131", "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=1", ""},
+		{"20240101", "FREQ=DAILY;COUNT=0", ""},
+		{"20240101", "FREQ=DAILY;COUNT=3;UNTIL=20240201", ""},
+	})
+
+	day := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format("20060102")
+	}
+	get := func(id string) map[string]any {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]any
+		assert.NoError(t, json.Unmarshal(body, &task))
+		return task
+	}
+
+	for _, v := range []struct {
+		name   string
+		fields map[string]any
+		dates  []string
+	}{
+		{"count", map[string]any{"repeat": "d 1", "count": "3"}, []string{day(2), day(3)}},
+		{"until", map[string]any{"repeat": "d 2", "until": day(4)}, []string{day(3)}},
+		{"until on the last date", map[string]any{"repeat": "d 2", "until": day(5)}, []string{day(3), day(5)}},
+		{"RRULE count", map[string]any{"repeat": "FREQ=DAILY;COUNT=2"}, []string{day(2)}},
+		{"RRULE until", map[string]any{"repeat": "FREQ=WEEKLY;UNTIL=" + day(8)}, []string{day(8)}},
+	} {
+		fields := map[string]any{"date": day(1), "title": "Курс таблеток"}
+		for k, val := range v.fields {
+			fields[k] = val
+		}
+		ret, err := postJSON("api/task", fields, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Nil(t, ret["error"], v.name)
+		id := fmt.Sprint(ret["id"])
+
+		for i, want := range v.dates {
+			ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Empty(t, ret, v.name)
+			task := get(id)
+			assert.Equal(t, want, task["date"], v.name)
+			if _, ok := v.fields["count"]; ok {
+				assert.Equal(t, fmt.Sprint(i+1), task["done"], v.name)
+			}
+		}
+
+		// Completing the last occurrence deletes the task.
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret, v.name)
+		notFoundTask(t, id)
+	}
+
+	for _, fields := range []map[string]any{
+		{"date": day(1), "title": "Без повтора", "count": "3"},
+		{"date": day(1), "title": "Без повтора", "until": day(5)},
+		{"date": day(1), "title": "Отрицательный", "repeat": "d 1", "count": "-1"},
+		{"date": day(1), "title": "Дата", "repeat": "d 1", "until": "2024-01-01"},
+	} {
+		ret, err := postJSON("api/task", fields, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], fields)
+	}
+}